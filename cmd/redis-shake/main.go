@@ -9,13 +9,21 @@ import (
 	"RedisShake/internal/utils"
 	"RedisShake/internal/writer"
 	"github.com/mcuadros/go-defaults"
+	"github.com/spf13/viper"
 	_ "net/http/pprof"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "rdb" {
+		runRDBCommand(os.Args[2:])
+		return
+	}
 	v := config.LoadConfig()
 
-	log.Init(config.Opt.Advanced.LogLevel, config.Opt.Advanced.LogFile, config.Opt.Advanced.Dir)
+	// the sync reader continues from the saved sync state if sync_aof is on
+	keepSyncState := v.IsSet("sync_reader") && (!v.IsSet("sync_reader.sync_aof") || v.GetBool("sync_reader.sync_aof"))
+	log.Init(config.Opt.Advanced.LogLevel, config.Opt.Advanced.LogFile, config.Opt.Advanced.Dir, keepSyncState)
 	utils.ChdirAndAcquireFileLock()
 	utils.SetNcpu()
 	utils.SetPprofPort()
@@ -60,6 +68,24 @@ func main() {
 		}
 		theReader = reader.NewRDBReader(opts)
 		log.Infof("create RdbReader: %v", opts.Filepath)
+	} else if v.IsSet("aof_reader") {
+		opts := new(reader.AOFReaderOptions)
+		defaults.SetDefaults(opts)
+		err := v.UnmarshalKey("aof_reader", opts)
+		if err != nil {
+			log.Panicf("failed to read the AOFReader config entry. err: %v", err)
+		}
+		theReader = reader.NewAOFReader(opts)
+		log.Infof("create AOFReader: %v", opts.Filepath)
+	} else if v.IsSet("file_reader") {
+		opts := new(reader.FileReaderOptions)
+		defaults.SetDefaults(opts)
+		err := v.UnmarshalKey("file_reader", opts)
+		if err != nil {
+			log.Panicf("failed to read the FileReader config entry. err: %v", err)
+		}
+		theReader = reader.NewFileReader(opts)
+		log.Infof("create FileReader: %v", opts.Dir)
 	} else {
 		log.Panicf("no reader config entry found")
 	}
@@ -80,6 +106,26 @@ func main() {
 			theWriter = writer.NewRedisStandaloneWriter(opts)
 			log.Infof("create RedisStandaloneWriter: %v", opts.Address)
 		}
+	} else if v.IsSet("rdb_writer") {
+		checkRDBWriterReader(v)
+		opts := new(writer.RdbWriterOptions)
+		defaults.SetDefaults(opts)
+		err := v.UnmarshalKey("rdb_writer", opts)
+		if err != nil {
+			log.Panicf("failed to read the RdbWriter config entry. err: %v", err)
+		}
+		opts.DeduplicateKeys = reader.MayDuplicateKeys(theReader)
+		theWriter = writer.NewRDBWriter(opts)
+		log.Infof("create RdbWriter: %v", opts.Filepath)
+	} else if v.IsSet("file_writer") {
+		opts := new(writer.FileWriterOptions)
+		defaults.SetDefaults(opts)
+		err := v.UnmarshalKey("file_writer", opts)
+		if err != nil {
+			log.Panicf("failed to read the FileWriter config entry. err: %v", err)
+		}
+		theWriter = writer.NewFileWriter(opts)
+		log.Infof("create FileWriter: %v", opts.Dir)
 	} else {
 		log.Panicf("no writer config entry found")
 	}
@@ -99,6 +145,7 @@ func main() {
 		log.Debugf("function before: %v", e)
 		entries := function.RunFunction(e)
 		log.Debugf("function after: %v", entries)
+		e.SplitWritten(entries)
 
 		for _, entry := range entries {
 			entry.Parse()
@@ -111,3 +158,20 @@ func main() {
 	utils.ReleaseFileLock() // Release file lock
 	log.Infof("all done")
 }
+
+// checkRDBWriterReader rejects the readers that send commands other than
+// RESTORE, which can not be written to rdb.
+func checkRDBWriterReader(v *viper.Viper) {
+	switch {
+	case v.IsSet("sync_reader") && (!v.IsSet("sync_reader.sync_aof") || v.GetBool("sync_reader.sync_aof")):
+		log.Panicf("rdb_writer can only write the rdb of sync_reader, please set sync_reader.sync_aof = false")
+	case v.IsSet("scan_reader") && v.GetBool("scan_reader.ksn"):
+		log.Panicf("rdb_writer can not write the commands of keyspace notifications, please set scan_reader.ksn = false")
+	case v.IsSet("scan_reader") && v.GetBool("scan_reader.fetch_by_type"):
+		log.Panicf("rdb_writer can not write the commands of fetch_by_type, please set scan_reader.fetch_by_type = false")
+	case v.IsSet("aof_reader") || v.IsSet("file_reader"):
+		log.Panicf("rdb_writer can not write the commands of aof_reader and file_reader")
+	case config.Opt.Advanced.ForceRewrite:
+		log.Panicf("rdb_writer can not write the commands of force_rewrite, please set advanced.force_rewrite = false")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb"
+	"RedisShake/internal/status"
+	"RedisShake/internal/utils"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mcuadros/go-defaults"
+	"os"
+	"strconv"
+)
+
+func rdbUsage() {
+	fmt.Println("Usage: redis-shake rdb verify <rdb file>")
+	fmt.Println("       redis-shake rdb analyze [-format json|csv] [-top N] [-output file] <rdb file>")
+	fmt.Println("  verify:  parse the rdb file and check its CRC64 checksum")
+	fmt.Println("  analyze: report the keys of the rdb file, a summary in json or one line per key in csv")
+	os.Exit(1)
+}
+
+// runRDBCommand runs the rdb subcommands, which work on rdb files without
+// any redis instance or config file.
+func runRDBCommand(args []string) {
+	if len(args) < 1 {
+		rdbUsage()
+	}
+	defaults.SetDefaults(&config.Opt)
+	log.InitConsole("info")
+	status.InitCounters() // such as the skipped keys of unknown modules
+	switch args[0] {
+	case "verify":
+		if len(args) != 2 {
+			rdbUsage()
+		}
+		verifyRDB(utils.GetAbsPath(args[1]))
+	case "analyze":
+		analyzeRDB(args[1:])
+	default:
+		rdbUsage()
+	}
+}
+
+// verifyRDB parses the rdb file and exits with an error if it is truncated or
+// corrupted, the values are decoded on all cpu cores and discarded.
+func verifyRDB(path string) {
+	config.Opt.Advanced.RDBParseWorkers = 0
+	ch := make(chan *entry.Entry, 1024)
+	done := make(chan struct{})
+	var entries int64
+	go func() {
+		for range ch {
+			entries++
+		}
+		close(done)
+	}()
+	ld := rdb.NewLoader("verify", nil, path, ch)
+	ld.ParseRDB()
+	close(ch)
+	<-done
+	log.Infof("rdb file is valid. file=[%s], size=[%d], entries=[%d]", path, utils.GetFileSize(path), entries)
+}
+
+func analyzeRDB(args []string) {
+	flags := flag.NewFlagSet("analyze", flag.ExitOnError)
+	format := flags.String("format", "json", "json: a summary with the biggest keys, csv: one line per key")
+	top := flags.Int("top", 100, "the number of biggest keys in the json report")
+	output := flags.String("output", "", "the file to write the report to, stdout if empty")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 || (*format != "json" && *format != "csv") {
+		rdbUsage()
+	}
+	path := utils.GetAbsPath(flags.Arg(0))
+
+	out := os.Stdout
+	if *output != "" {
+		var err error
+		out, err = os.Create(*output)
+		if err != nil {
+			log.Panicf("create file failed. file=[%s], error=[%v]", *output, err)
+		}
+		defer func() {
+			if err := out.Close(); err != nil {
+				log.Panicf("close file failed. file=[%s], error=[%v]", *output, err)
+			}
+		}()
+	}
+
+	if *format == "csv" {
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"db", "key", "type", "module", "size", "expire_at"})
+		ld := rdb.NewAnalyzeLoader(path, nil, func(info *rdb.KeyInfo) {
+			_ = w.Write([]string{strconv.Itoa(info.DbId), info.Key, info.Type, info.Module,
+				strconv.FormatInt(info.Size, 10), strconv.FormatInt(info.ExpireAt, 10)})
+		})
+		ld.ParseRDB()
+		if err := ld.AnalyzeError(); err != nil {
+			log.Warnf("the report is incomplete. error=[%v]", err)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Panicf("write csv failed. error=[%v]", err)
+		}
+		return
+	}
+
+	report := rdb.NewAnalyzeReport(path, *top)
+	ld := rdb.NewAnalyzeLoader(path, report.AddAux, report.AddKey)
+	ld.ParseRDB()
+	if err := ld.AnalyzeError(); err != nil {
+		log.Warnf("the report is incomplete. error=[%v]", err)
+		report.Truncated = err.Error()
+	}
+	report.Finish()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(report); err != nil {
+		log.Panicf("write json failed. error=[%v]", err)
+	}
+}
@@ -34,12 +34,16 @@ export default defineConfig({
                             { text: 'Sync Reader', link: '/zh/reader/sync_reader' },
                             { text: 'Scan Reader', link: '/zh/reader/scan_reader' },
                             { text: 'RDB Reader', link: '/zh/reader/rdb_reader' },
+                            { text: 'AOF Reader', link: '/zh/reader/aof_reader' },
+                            { text: 'File Reader', link: '/zh/reader/file_reader' },
                         ]
                     },
                     {
                         text: 'Writer',
                         items: [
                             { text: 'Redis Writer', link: '/zh/writer/redis_writer' },
+                            { text: 'RDB Writer', link: '/zh/writer/rdb_writer' },
+                            { text: 'File Writer', link: '/zh/writer/file_writer' },
                         ]
                     },
                     {
@@ -88,12 +92,16 @@ export default defineConfig({
                             { text: 'Sync Reader', link: '/en/reader/sync_reader' },
                             { text: 'Scan Reader', link: '/en/reader/scan_reader' },
                             { text: 'RDB Reader', link: '/en/reader/rdb_reader' },
+                            { text: 'AOF Reader', link: '/en/reader/aof_reader' },
+                            { text: 'File Reader', link: '/en/reader/file_reader' },
                         ]
                     },
                     {
                         text: 'Writer',
                         items: [
                             { text: 'Redis Writer', link: '/en/writer/redis_writer' },
+                            { text: 'RDB Writer', link: '/en/writer/rdb_writer' },
+                            { text: 'File Writer', link: '/en/writer/file_writer' },
                         ]
                     },
                     {
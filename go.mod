@@ -5,7 +5,9 @@ go 1.20
 require (
 	github.com/dustin/go-humanize v1.0.1
 	github.com/go-stack/stack v1.8.1
+	github.com/klauspost/compress v1.17.4
 	github.com/mcuadros/go-defaults v1.2.0
+	github.com/pierrec/lz4/v4 v4.1.21
 	github.com/rs/zerolog v1.28.0
 	github.com/spf13/viper v1.15.0
 	github.com/theckman/go-flock v0.8.1
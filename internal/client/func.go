@@ -4,6 +4,8 @@ import (
 	"RedisShake/internal/client/proto"
 	"RedisShake/internal/log"
 	"bytes"
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -25,3 +27,24 @@ func (r *Redis) IsCluster() bool {
 	reply := r.DoWithStringReply("INFO", "Cluster")
 	return strings.Contains(reply, "cluster_enabled:1")
 }
+
+// ServerVersion returns the redis_version of INFO server as major.minor, such
+// as 7.2 for 7.2.4.
+func (r *Redis) ServerVersion() (float64, error) {
+	reply, err := String(r.TryDo("INFO", "server"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "redis_version:") {
+			continue
+		}
+		items := strings.Split(strings.TrimPrefix(line, "redis_version:"), ".")
+		if len(items) < 2 {
+			return 0, fmt.Errorf("invalid redis_version: %s", line)
+		}
+		return strconv.ParseFloat(items[0]+"."+items[1], 64)
+	}
+	return 0, fmt.Errorf("redis_version not found in INFO server")
+}
@@ -5,12 +5,14 @@ import (
 	"RedisShake/internal/log"
 	"bufio"
 	"crypto/tls"
+	"fmt"
 	"net"
 	"strconv"
 	"time"
 )
 
 type Redis struct {
+	conn        net.Conn
 	reader      *bufio.Reader
 	writer      *bufio.Writer
 	protoReader *proto.Reader
@@ -18,45 +20,63 @@ type Redis struct {
 }
 
 func NewRedisClient(address string, username string, password string, Tls bool) *Redis {
+	r, err := DialRedisClient(address, username, password, Tls)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	return r
+}
+
+// DialRedisClient is like NewRedisClient, but returns the error instead of
+// exiting, for callers that want to retry.
+func DialRedisClient(address string, username string, password string, Tls bool) (*Redis, error) {
 	r := new(Redis)
-	var conn net.Conn
 	var dialer net.Dialer
 	var err error
 	dialer.Timeout = 3 * time.Second
 	if Tls {
-		conn, err = tls.DialWithDialer(&dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+		r.conn, err = tls.DialWithDialer(&dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
 	} else {
-		conn, err = dialer.Dial("tcp", address)
+		r.conn, err = dialer.Dial("tcp", address)
 	}
 	if err != nil {
-		log.Panicf("dial failed. address=[%s], tls=[%v], err=[%v]", address, Tls, err)
+		return nil, fmt.Errorf("dial failed. address=[%s], tls=[%v], err=[%v]", address, Tls, err)
 	}
 
-	r.reader = bufio.NewReader(conn)
-	r.writer = bufio.NewWriter(conn)
+	r.reader = bufio.NewReader(r.conn)
+	r.writer = bufio.NewWriter(r.conn)
 	r.protoReader = proto.NewReader(r.reader)
 	r.protoWriter = proto.NewWriter(r.writer)
 
 	// auth
 	if password != "" {
-		var reply string
+		args := []string{"auth", password}
 		if username != "" {
-			reply = r.DoWithStringReply("auth", username, password)
-		} else {
-			reply = r.DoWithStringReply("auth", password)
+			args = []string{"auth", username, password}
+		}
+		reply, err := String(r.TryDo(args...))
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("auth failed. address=[%s], err=[%v]", address, err)
 		}
 		if reply != "OK" {
-			log.Panicf("auth failed with reply: %s", reply)
+			r.Close()
+			return nil, fmt.Errorf("auth failed with reply: %s", reply)
 		}
 	}
 
 	// ping to test connection
-	reply := r.DoWithStringReply("ping")
+	reply, err := String(r.TryDo("ping"))
+	if err != nil {
+		r.Close()
+		return nil, fmt.Errorf("ping failed. address=[%s], err=[%v]", address, err)
+	}
 	if reply != "PONG" {
-		panic("ping failed with reply: " + reply)
+		r.Close()
+		return nil, fmt.Errorf("ping failed with reply: %s", reply)
 	}
 
-	return r
+	return r, nil
 }
 
 func (r *Redis) DoWithStringReply(args ...string) string {
@@ -80,16 +100,33 @@ func (r *Redis) Do(args ...string) interface{} {
 	return reply
 }
 
+// TryDo is like Do, but returns the error instead of exiting.
+func (r *Redis) TryDo(args ...string) (interface{}, error) {
+	err := r.TrySend(args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.Receive()
+}
+
 func (r *Redis) Send(args ...string) {
+	err := r.TrySend(args...)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+}
+
+// TrySend is like Send, but returns the error instead of exiting.
+func (r *Redis) TrySend(args ...string) error {
 	argsInterface := make([]interface{}, len(args))
 	for inx, item := range args {
 		argsInterface[inx] = item
 	}
 	err := r.protoWriter.WriteArgs(argsInterface)
 	if err != nil {
-		log.Panicf(err.Error())
+		return err
 	}
-	r.flush()
+	return r.writer.Flush()
 }
 
 func (r *Redis) SendBytes(buf []byte) {
@@ -119,6 +156,20 @@ func (r *Redis) ReceiveString() string {
 	return reply.(string)
 }
 
+// SetReadDeadline sets the deadline for future reads on the connection.
+func (r *Redis) SetReadDeadline(t time.Time) {
+	err := r.conn.SetReadDeadline(t)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+}
+
+// Close closes the connection, errors are ignored because the connection may
+// be broken already.
+func (r *Redis) Close() {
+	_ = r.conn.Close()
+}
+
 func (r *Redis) BufioReader() *bufio.Reader {
 	return r.reader
 }
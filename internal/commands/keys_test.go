@@ -42,6 +42,25 @@ func TestCalcKeys(t *testing.T) {
 	}
 }
 
+func TestCalcKeysModules(t *testing.T) {
+	tests := []struct {
+		argv  []string
+		group string
+		keys  []string
+	}{
+		{[]string{"JSON.SET", "doc", "$", `{"a":1}`}, "JSON", []string{"doc"}},
+		{[]string{"TS.CREATE", "ts", "RETENTION", "0", "ENCODING", "COMPRESSED"}, "TIMESERIES", []string{"ts"}},
+		{[]string{"TS.MADD", "ts1", "1", "1.5", "ts2", "2", "2.5"}, "TIMESERIES", []string{"ts1", "ts2"}},
+		{[]string{"TS.CREATERULE", "src", "dest", "AGGREGATION", "avg", "60000"}, "TIMESERIES", []string{"src", "dest"}},
+	}
+	for _, test := range tests {
+		cmd, group, keys, _ := CalcKeys(test.argv)
+		if cmd != test.argv[0] || group != test.group || !testEq(keys, test.keys) {
+			t.Errorf("CalcKeys(%v) failed. cmd=%s, group=%s, keys=%v", test.argv, cmd, group, keys)
+		}
+	}
+}
+
 func TestKeyHash(t *testing.T) {
 	ret := keyHash("abcde")
 	if ret != 16097 {
@@ -36,11 +36,42 @@ type AdvancedOptions struct {
 	TargetRedisClientMaxQuerybufLen int64  `mapstructure:"target_redis_client_max_querybuf_len" default:"1024000000"`
 	TargetRedisProtoMaxBulkLen      uint64 `mapstructure:"target_redis_proto_max_bulk_len" default:"512000000"`
 
+	// the keys that are too large to RESTORE are rewritten as commands, each
+	// adds at most RewriteBatchCount elements and RewriteBatchBytes bytes
+	RewriteBatchCount uint64 `mapstructure:"rewrite_batch_count" default:"512"`
+	RewriteBatchBytes uint64 `mapstructure:"rewrite_batch_bytes" default:"16777216"`
+	// always rewrite the values as commands instead of RESTORE, for the targets
+	// that do not support the DUMP payload of redis
+	ForceRewrite bool `mapstructure:"force_rewrite" default:"false"`
+	// the number of goroutines that decode the values of rdb, 1 means decoding
+	// in the goroutine that reads the rdb, 0 means the number of cpu cores
+	RDBParseWorkers int `mapstructure:"rdb_parse_workers" default:"1"`
+
 	AwsPSync string `mapstructure:"aws_psync" default:""` // 10.0.0.1:6379@nmfu2sl5osync,10.0.0.1:6379@xhma21xfkssync
+
+	// skip the keys in rdb that have already expired, instead of writing them
+	// with a 1ms TTL
+	DropExpiredKeys bool `mapstructure:"drop_expired_keys" default:"false"`
+	// rewrite the relative expire commands in aof, such as EXPIRE and SETEX, to
+	// the absolute ones, using the time the command is received from source
+	AofAbsoluteExpire bool `mapstructure:"aof_absolute_expire" default:"false"`
 }
 
 type ModuleOptions struct {
 	TargetMBbloomVersion int `mapstructure:"target_mbbloom_version" default:"0"` // v1.0.0 <=> 10000
+
+	// What to do with the values of modules that redis-shake does not know:
+	// passthrough: restore the value by RESTORE, the target must have the module loaded.
+	// skip:        skip the key with a warning, counted in skipped_keys_count of status.
+	// panic:       redis-shake will stop.
+	UnknownModuleBehavior string `mapstructure:"unknown_module_behavior" default:"passthrough"`
+
+	// What to do with the keys that must be rewritten but can not be, such as
+	// a non-empty Count-Min Sketch or a module key fetched by fetch_by_type:
+	// panic: redis-shake will stop.
+	// skip:  skip the key with a warning, counted in skipped_keys_count of status.
+	//        The keys are lost in the target.
+	RewriteFailureBehavior string `mapstructure:"rewrite_failure_behavior" default:"panic"`
 }
 
 func (opt *AdvancedOptions) GetPSyncCommand(address string) string {
@@ -54,10 +85,16 @@ func (opt *AdvancedOptions) GetPSyncCommand(address string) string {
 	return ""
 }
 
+// TargetInfo is detected by the writer when it connects to the target.
+type TargetInfo struct {
+	Version float64 // such as 7.2, the lowest one of all nodes, 0 if unknown
+}
+
 type ShakeOptions struct {
 	Function string `mapstructure:"function" default:""`
 	Advanced AdvancedOptions
 	Module   ModuleOptions
+	Target   TargetInfo `mapstructure:"-"`
 }
 
 var Opt ShakeOptions
@@ -5,7 +5,12 @@ import (
 	"RedisShake/internal/commands"
 	"RedisShake/internal/log"
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"strings"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 type Entry struct {
@@ -20,6 +25,10 @@ type Entry struct {
 
 	// for stat
 	SerializedSize int64
+
+	// OnWritten is called by the writer after the target applied the entry,
+	// nil if the reader does not track it.
+	OnWritten func()
 }
 
 func NewEntry() *Entry {
@@ -27,6 +36,35 @@ func NewEntry() *Entry {
 	return e
 }
 
+// Written tells the reader that the entry is applied by the target.
+func (e *Entry) Written() {
+	if e.OnWritten != nil {
+		e.OnWritten()
+	}
+}
+
+// SplitWritten makes e written after all the entries are written, such as the
+// entries returned by the function. The entries may include e itself. e is
+// written at once if there is no entry.
+func (e *Entry) SplitWritten(entries []*Entry) {
+	onWritten := e.OnWritten
+	if onWritten == nil {
+		return
+	}
+	if len(entries) == 0 {
+		onWritten()
+		return
+	}
+	remaining := int64(len(entries))
+	for _, derived := range entries {
+		derived.OnWritten = func() {
+			if atomic.AddInt64(&remaining, -1) == 0 {
+				onWritten()
+			}
+		}
+	}
+}
+
 func (e *Entry) String() string {
 	str := strings.Join(e.Argv, " ")
 	if len(str) > 100 {
@@ -55,3 +93,83 @@ func (e *Entry) Parse() {
 	e.CmdName, e.Group, e.Keys, e.KeyIndexes = commands.CalcKeys(e.Argv)
 	e.Slots = commands.CalcSlots(e.Keys)
 }
+
+// FileSuffix returns the suffix of the files of file_writer in the format,
+// "resp" for Serialize and "json" for MarshalJSONLine.
+func FileSuffix(format string) string {
+	switch format {
+	case "resp":
+		return ".aof"
+	case "json":
+		return ".jsonl"
+	}
+	log.Panicf("invalid file format: [%s], should be resp or json", format)
+	return ""
+}
+
+// jsonLine is a line of the json format of file_writer.
+type jsonLine struct {
+	Timestamp int64    `json:"ts"` // in milliseconds
+	DbId      int      `json:"db"`
+	Cmd       string   `json:"cmd"`
+	Keys      []string `json:"keys"`
+	Argv      []string `json:"argv"`
+	Base64    bool     `json:"base64,omitempty"` // keys and argv are encoded by base64 if any of them is not utf-8
+}
+
+// MarshalJSONLine returns the entry as a line of json ending with '\n', the
+// entry should be parsed. timestamp is in milliseconds.
+func (e *Entry) MarshalJSONLine(timestamp int64) []byte {
+	line := jsonLine{Timestamp: timestamp, DbId: e.DbId, Cmd: e.CmdName, Keys: e.Keys, Argv: e.Argv}
+	if line.Keys == nil {
+		line.Keys = []string{}
+	}
+	for _, arg := range e.Argv {
+		if !utf8.ValidString(arg) {
+			line.Base64 = true
+			line.Keys = encodeBase64(line.Keys)
+			line.Argv = encodeBase64(line.Argv)
+			break
+		}
+	}
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(&line); err != nil {
+		log.Panicf(err.Error())
+	}
+	return buf.Bytes()
+}
+
+// ParseJSONLine parses a line of MarshalJSONLine, it returns the entry and
+// the timestamp in milliseconds.
+func ParseJSONLine(data []byte) (*Entry, int64, error) {
+	var line jsonLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return nil, 0, err
+	}
+	if len(line.Argv) == 0 {
+		return nil, 0, fmt.Errorf("argv is empty")
+	}
+	if line.Base64 {
+		for inx, arg := range line.Argv {
+			decoded, err := base64.StdEncoding.DecodeString(arg)
+			if err != nil {
+				return nil, 0, err
+			}
+			line.Argv[inx] = string(decoded)
+		}
+	}
+	e := NewEntry()
+	e.DbId = line.DbId
+	e.Argv = line.Argv
+	return e, line.Timestamp, nil
+}
+
+func encodeBase64(items []string) []string {
+	encoded := make([]string, len(items))
+	for inx, item := range items {
+		encoded[inx] = base64.StdEncoding.EncodeToString([]byte(item))
+	}
+	return encoded
+}
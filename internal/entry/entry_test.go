@@ -0,0 +1,48 @@
+package entry
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestJSONLine(t *testing.T) {
+	tests := map[string]struct {
+		argv   []string
+		base64 bool
+	}{
+		"utf-8":     {[]string{"SET", "key", "值 \"<&>\"\n"}, false},
+		"binary":    {[]string{"SET", "key", "\xff\x00\x80"}, true},
+		"no key":    {[]string{"PING"}, false},
+		"empty arg": {[]string{"SET", "", ""}, false},
+	}
+	for name, test := range tests {
+		e := NewEntry()
+		e.DbId = 3
+		e.Argv = test.argv
+		e.Parse()
+		line := e.MarshalJSONLine(1628217470123)
+		if line[len(line)-1] != '\n' || bytes.IndexByte(line[:len(line)-1], '\n') != -1 {
+			t.Errorf("%s: got %q, want a line", name, line)
+		}
+		if got := bytes.Contains(line, []byte(`"base64":true`)); got != test.base64 {
+			t.Errorf("%s: got base64=%v in %q", name, got, line)
+		}
+		parsed, timestamp, err := ParseJSONLine(line)
+		if err != nil || timestamp != 1628217470123 || parsed.DbId != 3 || !reflect.DeepEqual(parsed.Argv, test.argv) {
+			t.Errorf("%s: got %+v, %d, error=[%v], want %q", name, parsed, timestamp, err, test.argv)
+		}
+	}
+}
+
+func TestParseJSONLineError(t *testing.T) {
+	for _, line := range []string{
+		`{"ts":1,"db":0,"argv":[]}`,
+		`{"ts":1,"db":0,"argv":["SET","k","!"],"base64":true}`,
+		`{"ts":1,"db":0,"argv":["SET"`,
+	} {
+		if _, _, err := ParseJSONLine([]byte(line)); err == nil {
+			t.Errorf("%s: want an error", line)
+		}
+	}
+}
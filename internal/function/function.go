@@ -26,6 +26,7 @@ func Init() {
 // KEY_INDEXES
 // SLOTS
 // ARGV
+// LIBRARY
 
 // shake.call(DB, ARGV)
 // shake.log()
@@ -61,6 +62,7 @@ func RunFunction(e *entry.Entry) []*entry.Entry {
 		argv.Append(lua.LString(arg))
 	}
 	L.SetGlobal("ARGV", argv)
+	L.SetGlobal("LIBRARY", lua.LString(libraryName(e)))
 	shake := L.NewTypeMetatable("shake")
 	L.SetGlobal("shake", shake)
 
@@ -88,3 +90,27 @@ func RunFunction(e *entry.Entry) []*entry.Entry {
 
 	return entries
 }
+
+// libraryName returns the library name of FUNCTION LOAD and FUNCTION DELETE,
+// or "" for other commands.
+func libraryName(e *entry.Entry) string {
+	switch e.CmdName {
+	case "FUNCTION-DELETE":
+		if len(e.Argv) > 2 {
+			return e.Argv[2]
+		}
+	case "FUNCTION-LOAD":
+		// the code is the last argument and starts with: #!<engine> name=<library> [key=value ...]
+		code := e.Argv[len(e.Argv)-1]
+		if !strings.HasPrefix(code, "#!") {
+			return ""
+		}
+		shebang := strings.SplitN(code, "\n", 2)[0]
+		for _, field := range strings.Fields(shebang)[1:] {
+			if strings.HasPrefix(field, "name=") {
+				return strings.TrimPrefix(field, "name=")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package function
+
+import (
+	"reflect"
+	"testing"
+
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+)
+
+func TestRunFunctionLibrary(t *testing.T) {
+	defer func(function string) {
+		config.Opt.Function = function
+		Init()
+	}(config.Opt.Function)
+	config.Opt.Function = `
+if LIBRARY == "internal" then
+  return
+end
+if LIBRARY ~= "" then
+  shake.log(LIBRARY)
+end
+shake.call(DB, ARGV)
+`
+	Init()
+
+	for _, c := range []struct {
+		argv []string
+		want bool // passed to the writer
+	}{
+		{[]string{"function", "load", "replace", "#!lua name=internal\nredis.register_function('f', function() return 1 end)"}, false},
+		{[]string{"function", "load", "#!lua name=mylib engine=x\nredis.register_function('g', function() return 2 end)"}, true},
+		{[]string{"function", "delete", "internal"}, false},
+		{[]string{"function", "delete", "mylib"}, true},
+		{[]string{"set", "internal", "v"}, true},
+	} {
+		e := entry.NewEntry()
+		e.Argv = c.argv
+		e.Parse()
+		entries := RunFunction(e)
+		if got := len(entries) == 1 && reflect.DeepEqual(entries[0].Argv, c.argv); got != c.want {
+			t.Errorf("%q: got %d entries, want passed=%v", c.argv, len(entries), c.want)
+		}
+	}
+}
@@ -9,28 +9,18 @@ import (
 
 var logger zerolog.Logger
 
-func Init(level string, file string, dir string) {
-	// log level
-	switch level {
-	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	case "warn":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
-	default:
-		panic(fmt.Sprintf("unknown log level: %s", level))
-	}
+// Init cleans dir and logs to the file in it. The sync state of the sync
+// readers is kept if keepSyncState is true, so that they can continue from
+// where they stopped after a restart.
+func Init(level string, file string, dir string, keepSyncState bool) {
+	setLevel(level)
 
 	// dir
 	dir, err := filepath.Abs(dir)
 	if err != nil {
 		panic(fmt.Sprintf("failed to determine current directory: %v", err))
 	}
-	err = os.RemoveAll(dir)
-	if err != nil {
-		panic(fmt.Sprintf("remove dir failed. dir=[%s], error=[%v]", dir, err))
-	}
+	cleanDir(dir, keepSyncState)
 	err = os.MkdirAll(dir, 0777)
 	if err != nil {
 		panic(fmt.Sprintf("mkdir failed. dir=[%s], error=[%v]", dir, err))
@@ -47,3 +37,65 @@ func Init(level string, file string, dir string) {
 	logger = zerolog.New(multi).With().Timestamp().Logger()
 	Infof("log_level: [%v], log_file: [%v]", level, path)
 }
+
+// cleanDir removes the content of dir. If keepSyncState is true, the
+// directories with a sync_state.json keep it and their aof files.
+func cleanDir(dir string, keepSyncState bool) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		panic(fmt.Sprintf("read dir failed. dir=[%s], error=[%v]", dir, err))
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if keepSyncState && entry.IsDir() {
+			if _, err := os.Stat(filepath.Join(path, "sync_state.json")); err == nil {
+				cleanSyncStateDir(path)
+				continue
+			}
+		}
+		err = os.RemoveAll(path)
+		if err != nil {
+			panic(fmt.Sprintf("remove dir failed. dir=[%s], error=[%v]", path, err))
+		}
+	}
+}
+
+func cleanSyncStateDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		panic(fmt.Sprintf("read dir failed. dir=[%s], error=[%v]", dir, err))
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && (entry.Name() == "sync_state.json" || filepath.Ext(entry.Name()) == ".aof") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		err = os.RemoveAll(path)
+		if err != nil {
+			panic(fmt.Sprintf("remove file failed. path=[%s], error=[%v]", path, err))
+		}
+	}
+}
+
+// InitConsole logs to stderr only, for the subcommands that do not load the
+// config file and may write their results to stdout.
+func InitConsole(level string) {
+	setLevel(level)
+	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "2006-01-02 15:04:05"}
+	logger = zerolog.New(consoleWriter).With().Timestamp().Logger()
+}
+
+func setLevel(level string) {
+	switch level {
+	case "debug":
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	case "info":
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	case "warn":
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	default:
+		panic(fmt.Sprintf("unknown log level: %s", level))
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCleanDir(t *testing.T) {
+	for _, keepSyncState := range []bool{true, false} {
+		dir := t.TempDir()
+		files := []string{
+			"shake.log",
+			"reader_127.0.0.1_6379/sync_state.json",
+			"reader_127.0.0.1_6379/0.aof",
+			"reader_127.0.0.1_6379/1024.aof",
+			"reader_127.0.0.1_6379/dump.rdb",
+			"reader_127.0.0.1_6380/0.aof", // no sync state
+		}
+		for _, file := range files {
+			path := filepath.Join(dir, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("x"), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cleanDir(dir, keepSyncState)
+
+		var left []string
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				rel, _ := filepath.Rel(dir, path)
+				left = append(left, rel)
+			}
+			return nil
+		})
+		sort.Strings(left)
+		var want []string
+		if keepSyncState {
+			want = []string{"reader_127.0.0.1_6379/0.aof", "reader_127.0.0.1_6379/1024.aof", "reader_127.0.0.1_6379/sync_state.json"}
+		}
+		if len(left) != len(want) {
+			t.Fatalf("keepSyncState=%v, left=%v, want=%v", keepSyncState, left, want)
+		}
+		for i := range want {
+			if left[i] != want[i] {
+				t.Fatalf("keepSyncState=%v, left=%v, want=%v", keepSyncState, left, want)
+			}
+		}
+	}
+}
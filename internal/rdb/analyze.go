@@ -0,0 +1,61 @@
+package rdb
+
+import (
+	"RedisShake/internal/rdb/types"
+	"fmt"
+	"io"
+)
+
+// KeyInfo describes a key of the rdb without its value.
+type KeyInfo struct {
+	DbId     int    `json:"db"`
+	Key      string `json:"key"`
+	Type     string `json:"type"`             // such as "hash", see types.TypeName
+	Module   string `json:"module,omitempty"` // module type name if Type is "module"
+	Size     int64  `json:"size"`             // bytes of the value in rdb
+	ExpireAt int64  `json:"expire_at"`        // absolute expire time in milliseconds, 0 if no expire
+}
+
+// NewAnalyzeLoader returns a loader that reads the rdb file without creating
+// any command. auxFunc is called for each aux field and keyFunc for each key.
+func NewAnalyzeLoader(filPath string, auxFunc func(key, value string), keyFunc func(info *KeyInfo)) *Loader {
+	ld := new(Loader)
+	ld.filPath = filPath
+	ld.name = "analyze"
+	ld.auxFunc = auxFunc
+	ld.keyFunc = keyFunc
+	return ld
+}
+
+// analyzeValue skips the value of the record without decoding it and calls
+// keyFunc. The end of a value that can not be framed, such as an unknown type,
+// is unknown, so the key is reported with type "unknown" and the analysis
+// stops there, see AnalyzeError.
+func (ld *Loader) analyzeValue(rd io.Reader, rec *keyRecord) {
+	info := &KeyInfo{
+		DbId:     rec.dbId,
+		Key:      rec.key,
+		Type:     types.TypeName(rec.typeByte),
+		ExpireAt: rec.expireAt,
+	}
+	counter := &countingReader{rd: rd, n: &info.Size}
+	framed := false
+	switch info.Type {
+	case types.UnknownType:
+	case types.ModuleType:
+		info.Module, framed = types.SkipModuleObject(counter, rec.typeByte)
+	default:
+		framed = types.SkipObject(counter, rec.typeByte, func() bool { return false })
+	}
+	if !framed {
+		info.Type = types.UnknownType
+		ld.analyzeErr = fmt.Errorf("the value of type byte %d can not be skipped, the keys after it are not analyzed. key=[%s]", rec.typeByte, rec.key)
+	}
+	ld.keyFunc(info)
+}
+
+// AnalyzeError returns the error that stops the analysis before the end of
+// the rdb, nil if all the keys are analyzed.
+func (ld *Loader) AnalyzeError() error {
+	return ld.analyzeErr
+}
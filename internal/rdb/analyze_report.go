@@ -0,0 +1,162 @@
+package rdb
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	// upper bounds of the buckets of AnalyzeReport.SizeHistogram, in bytes
+	sizeBuckets = []int64{64, 1 << 10, 16 << 10, 256 << 10, 1 << 20, 16 << 20, 256 << 20}
+	// upper bounds of the buckets of AnalyzeReport.TTLHistogram, in milliseconds
+	ttlBuckets     = []int64{3600 * 1000, 24 * 3600 * 1000, 7 * 24 * 3600 * 1000, 30 * 24 * 3600 * 1000}
+	ttlBucketNames = []string{"1h", "1d", "7d", "30d"}
+)
+
+// AnalyzeReport summarizes the keys of an rdb file. The sizes are the bytes of
+// the values in rdb, which are usually smaller than the memory they take in
+// redis but keep the same proportion.
+type AnalyzeReport struct {
+	File    string            `json:"file"`
+	Aux     map[string]string `json:"aux"` // such as redis-ver and ctime
+	Keys    int64             `json:"keys"`
+	Bytes   int64             `json:"bytes"`
+	Expires int64             `json:"expires"` // keys with an expire time
+
+	DBs     map[int]*AnalyzeStat    `json:"dbs"`
+	Types   map[string]*AnalyzeStat `json:"types"`
+	Modules map[string]*AnalyzeStat `json:"modules"`
+
+	SizeHistogram []AnalyzeBucket `json:"size_histogram"`
+	TTLHistogram  []AnalyzeBucket `json:"ttl_histogram"` // relative to Now
+	BiggestKeys   []*KeyInfo      `json:"biggest_keys"`
+	Now           int64           `json:"now"`                 // in milliseconds
+	Truncated     string          `json:"truncated,omitempty"` // why the keys after the last one are not analyzed
+
+	top    int
+	topKey keyHeap
+}
+
+type AnalyzeStat struct {
+	Keys    int64            `json:"keys"`
+	Bytes   int64            `json:"bytes"`
+	Expires int64            `json:"expires,omitempty"`
+	Types   map[string]int64 `json:"types,omitempty"` // keys of each type, only for dbs
+}
+
+type AnalyzeBucket struct {
+	Name  string `json:"name"`
+	Keys  int64  `json:"keys"`
+	Bytes int64  `json:"bytes"`
+}
+
+// NewAnalyzeReport returns an empty report that keeps the top biggest keys.
+func NewAnalyzeReport(file string, top int) *AnalyzeReport {
+	r := &AnalyzeReport{
+		File:    file,
+		Aux:     make(map[string]string),
+		DBs:     make(map[int]*AnalyzeStat),
+		Types:   make(map[string]*AnalyzeStat),
+		Modules: make(map[string]*AnalyzeStat),
+		Now:     time.Now().UnixMilli(),
+		top:     top,
+	}
+	for _, b := range sizeBuckets {
+		r.SizeHistogram = append(r.SizeHistogram, AnalyzeBucket{Name: "<=" + strconv.FormatInt(b, 10)})
+	}
+	r.SizeHistogram = append(r.SizeHistogram, AnalyzeBucket{Name: ">" + strconv.FormatInt(sizeBuckets[len(sizeBuckets)-1], 10)})
+	r.TTLHistogram = append(r.TTLHistogram, AnalyzeBucket{Name: "no_expire"}, AnalyzeBucket{Name: "expired"})
+	for _, name := range ttlBucketNames {
+		r.TTLHistogram = append(r.TTLHistogram, AnalyzeBucket{Name: "<" + name})
+	}
+	r.TTLHistogram = append(r.TTLHistogram, AnalyzeBucket{Name: ">=" + ttlBucketNames[len(ttlBucketNames)-1]})
+	return r
+}
+
+func (r *AnalyzeReport) AddAux(key, value string) {
+	r.Aux[key] = value
+}
+
+func (r *AnalyzeReport) AddKey(info *KeyInfo) {
+	r.Keys++
+	r.Bytes += info.Size
+	if info.ExpireAt != 0 {
+		r.Expires++
+	}
+
+	db, ok := r.DBs[info.DbId]
+	if !ok {
+		db = &AnalyzeStat{Types: make(map[string]int64)}
+		r.DBs[info.DbId] = db
+	}
+	db.add(info)
+	db.Types[info.Type]++
+	addStat(r.Types, info.Type, info)
+	if info.Module != "" {
+		addStat(r.Modules, info.Module, info)
+	}
+
+	inx := sort.Search(len(sizeBuckets), func(i int) bool { return info.Size <= sizeBuckets[i] })
+	r.SizeHistogram[inx].add(info)
+	if info.ExpireAt == 0 {
+		r.TTLHistogram[0].add(info)
+	} else if ttl := info.ExpireAt - r.Now; ttl <= 0 {
+		r.TTLHistogram[1].add(info)
+	} else {
+		inx = sort.Search(len(ttlBuckets), func(i int) bool { return ttl < ttlBuckets[i] })
+		r.TTLHistogram[2+inx].add(info)
+	}
+
+	if r.top > 0 {
+		heap.Push(&r.topKey, info)
+		if r.topKey.Len() > r.top {
+			heap.Pop(&r.topKey)
+		}
+	}
+}
+
+// Finish fills BiggestKeys, call it after all keys are added.
+func (r *AnalyzeReport) Finish() {
+	r.BiggestKeys = make([]*KeyInfo, r.topKey.Len())
+	for i := len(r.BiggestKeys) - 1; i >= 0; i-- {
+		r.BiggestKeys[i] = heap.Pop(&r.topKey).(*KeyInfo)
+	}
+}
+
+func addStat(stats map[string]*AnalyzeStat, name string, info *KeyInfo) {
+	s, ok := stats[name]
+	if !ok {
+		s = new(AnalyzeStat)
+		stats[name] = s
+	}
+	s.add(info)
+}
+
+func (s *AnalyzeStat) add(info *KeyInfo) {
+	s.Keys++
+	s.Bytes += info.Size
+	if info.ExpireAt != 0 {
+		s.Expires++
+	}
+}
+
+func (b *AnalyzeBucket) add(info *KeyInfo) {
+	b.Keys++
+	b.Bytes += info.Size
+}
+
+// keyHeap is a min-heap of keys by size.
+type keyHeap []*KeyInfo
+
+func (h keyHeap) Len() int            { return len(h) }
+func (h keyHeap) Less(i, j int) bool  { return h[i].Size < h[j].Size }
+func (h keyHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *keyHeap) Push(x interface{}) { *h = append(*h, x.(*KeyInfo)) }
+func (h *keyHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,73 @@
+package rdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeReport(t *testing.T) {
+	r := NewAnalyzeReport("dump.rdb", 2)
+	hour := int64(3600 * 1000)
+	keys := []*KeyInfo{
+		{DbId: 0, Key: "a", Type: "string", Size: 10},
+		{DbId: 0, Key: "b", Type: "hash", Size: 2000, ExpireAt: r.Now + 2*hour},
+		{DbId: 1, Key: "c", Type: "module", Module: "MBbloom--", Size: 1 << 30, ExpireAt: r.Now - 1},
+		{DbId: 1, Key: "d", Type: "string", Size: 64, ExpireAt: r.Now + 60*24*hour},
+		{DbId: 1, Key: "e", Type: "string", Size: 65},
+	}
+	for _, info := range keys {
+		r.AddKey(info)
+	}
+	r.Finish()
+
+	if r.Keys != 5 || r.Bytes != 10+2000+(1<<30)+64+65 || r.Expires != 3 {
+		t.Errorf("got keys=%d, bytes=%d, expires=%d", r.Keys, r.Bytes, r.Expires)
+	}
+	wantDB1 := &AnalyzeStat{Keys: 3, Bytes: (1 << 30) + 64 + 65, Expires: 2, Types: map[string]int64{"module": 1, "string": 2}}
+	if len(r.DBs) != 2 || !reflect.DeepEqual(r.DBs[1], wantDB1) {
+		t.Errorf("got db 1 %+v, want %+v", r.DBs[1], wantDB1)
+	}
+	if s := r.Types["string"]; s.Keys != 3 || s.Bytes != 10+64+65 || s.Expires != 1 {
+		t.Errorf("got string %+v", s)
+	}
+	if s := r.Modules["MBbloom--"]; len(r.Modules) != 1 || s.Keys != 1 {
+		t.Errorf("got modules %+v", r.Modules)
+	}
+
+	sizes := map[string]int64{}
+	for _, b := range r.SizeHistogram {
+		sizes[b.Name] = b.Keys
+	}
+	wantSizes := map[string]int64{"<=64": 2, "<=1024": 1, "<=16384": 1, "<=262144": 0, "<=1048576": 0,
+		"<=16777216": 0, "<=268435456": 0, ">268435456": 1}
+	if !reflect.DeepEqual(sizes, wantSizes) {
+		t.Errorf("got size histogram %v, want %v", sizes, wantSizes)
+	}
+
+	ttls := map[string]int64{}
+	for _, b := range r.TTLHistogram {
+		ttls[b.Name] = b.Keys
+	}
+	wantTTLs := map[string]int64{"no_expire": 2, "expired": 1, "<1h": 0, "<1d": 1, "<7d": 0, "<30d": 0, ">=30d": 1}
+	if !reflect.DeepEqual(ttls, wantTTLs) {
+		t.Errorf("got ttl histogram %v, want %v", ttls, wantTTLs)
+	}
+
+	// the biggest keys first
+	var biggest []string
+	for _, info := range r.BiggestKeys {
+		biggest = append(biggest, info.Key)
+	}
+	if want := []string{"c", "b"}; !reflect.DeepEqual(biggest, want) {
+		t.Errorf("got biggest keys %v, want %v", biggest, want)
+	}
+}
+
+func TestAnalyzeReportWithoutTop(t *testing.T) {
+	r := NewAnalyzeReport("dump.rdb", 0)
+	r.AddKey(&KeyInfo{Key: "a", Type: "string", Size: 1})
+	r.Finish()
+	if r.BiggestKeys == nil || len(r.BiggestKeys) != 0 {
+		t.Errorf("got biggest keys %v, want an empty list", r.BiggestKeys)
+	}
+}
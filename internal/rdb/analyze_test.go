@@ -0,0 +1,64 @@
+package rdb
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// analyzeTestRDB returns an rdb of version 11 with the entries and the
+// checksum disabled.
+func analyzeTestRDB(entries ...[]byte) []byte {
+	buf := []byte("REDIS0011")
+	for _, e := range entries {
+		buf = append(buf, e...)
+	}
+	buf = append(buf, kEOF)
+	return append(buf, make([]byte, 8)...)
+}
+
+func analyzeTestEntry(typeByte byte, key string, value []byte) []byte {
+	buf := []byte{typeByte, byte(len(key))}
+	buf = append(buf, key...)
+	return append(buf, value...)
+}
+
+func analyzeTestKeys(rdb []byte) ([]KeyInfo, error) {
+	var keys []KeyInfo
+	ld := NewAnalyzeLoader("", nil, func(info *KeyInfo) { keys = append(keys, *info) })
+	ld.src = bytes.NewReader(rdb)
+	ld.ParseRDB()
+	return keys, ld.AnalyzeError()
+}
+
+func TestAnalyzeLoader(t *testing.T) {
+	keys, err := analyzeTestKeys(analyzeTestRDB(
+		[]byte{kFlagSelect, 2},
+		analyzeTestEntry(1, "l", listValue("a", "bc")),
+		analyzeTestEntry(0, "s", []byte{3, 'a', 'b', 'c'}),
+	))
+	want := []KeyInfo{
+		{DbId: 2, Key: "l", Type: "list", Size: 6},
+		{DbId: 2, Key: "s", Type: "string", Size: 4},
+	}
+	if !reflect.DeepEqual(keys, want) || err != nil {
+		t.Errorf("got %+v, error=[%v], want %+v", keys, err, want)
+	}
+}
+
+func TestAnalyzeLoaderUnknownType(t *testing.T) {
+	for _, typeByte := range []byte{6 /* module type with version 1 */, 100} {
+		keys, err := analyzeTestKeys(analyzeTestRDB(
+			analyzeTestEntry(0, "s", []byte{1, 'a'}),
+			analyzeTestEntry(typeByte, "x", []byte{1, 2, 3}),
+			analyzeTestEntry(0, "t", []byte{1, 'b'}),
+		))
+		want := []KeyInfo{
+			{Key: "s", Type: "string", Size: 2},
+			{Key: "x", Type: "unknown"},
+		}
+		if !reflect.DeepEqual(keys, want) || err == nil {
+			t.Errorf("type %d: got %+v, error=[%v], want %+v and an error", typeByte, keys, err, want)
+		}
+	}
+}
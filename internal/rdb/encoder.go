@@ -0,0 +1,126 @@
+package rdb
+
+import (
+	"RedisShake/internal/log"
+	"RedisShake/internal/utils"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Encoder writes an rdb file. The values are written as the payloads of DUMP
+// without the footer, see ParseValueDump.
+type Encoder struct {
+	wr  *bufio.Writer
+	crc interface {
+		Update(p []byte)
+		Sum64() uint64
+	}
+	dbId  int // the db of the last SELECT, -1 before the first key
+	bytes int64
+}
+
+// NewEncoder writes the header of the rdb version to w.
+func NewEncoder(w io.Writer, version int) *Encoder {
+	enc := &Encoder{wr: bufio.NewWriterSize(w, 1<<20), crc: utils.NewDigest(), dbId: -1}
+	enc.write([]byte(fmt.Sprintf("REDIS%04d", version)))
+	return enc
+}
+
+func (enc *Encoder) write(p []byte) {
+	_, err := enc.wr.Write(p)
+	if err != nil {
+		log.Panicf("write rdb failed. error=[%v]", err)
+	}
+	enc.crc.Update(p)
+	enc.bytes += int64(len(p))
+}
+
+func (enc *Encoder) writeByte(b byte) {
+	enc.write([]byte{b})
+}
+
+func (enc *Encoder) writeLength(length uint64) {
+	var buf [9]byte
+	switch {
+	case length < 1<<6:
+		enc.writeByte(byte(length))
+	case length < 1<<14:
+		enc.write([]byte{byte(length>>8) | 0x40, byte(length)})
+	case length <= math.MaxUint32:
+		buf[0] = 0x80
+		binary.BigEndian.PutUint32(buf[1:], uint32(length))
+		enc.write(buf[:5])
+	default:
+		buf[0] = 0x81
+		binary.BigEndian.PutUint64(buf[1:], length)
+		enc.write(buf[:9])
+	}
+}
+
+func (enc *Encoder) writeString(s string) {
+	enc.writeLength(uint64(len(s)))
+	enc.write([]byte(s))
+}
+
+// Bytes returns the bytes written.
+func (enc *Encoder) Bytes() int64 {
+	return enc.bytes
+}
+
+func (enc *Encoder) WriteAux(key, value string) {
+	enc.writeByte(kFlagAUX)
+	enc.writeString(key)
+	enc.writeString(value)
+}
+
+// WriteFunction writes a function library, the code of FUNCTION LOAD.
+func (enc *Encoder) WriteFunction(code string) {
+	enc.writeByte(kFlagFunction2)
+	enc.writeString(code)
+}
+
+// WriteKey writes a key in the db. value is the payload of DUMP without the
+// type and the footer. expireAt is in milliseconds, 0 if no expire.
+func (enc *Encoder) WriteKey(dbId int, key string, typeByte byte, value []byte, expireAt, idle, freq int64) {
+	if dbId != enc.dbId {
+		enc.writeByte(kFlagSelect)
+		enc.writeLength(uint64(dbId))
+		enc.dbId = dbId
+	}
+	if expireAt != 0 {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], uint64(expireAt))
+		enc.writeByte(kFlagExpireMs)
+		enc.write(buf[:])
+	}
+	if idle != 0 {
+		enc.writeByte(kFlagIdle)
+		enc.writeLength(uint64(idle))
+	}
+	if freq != 0 {
+		enc.writeByte(kFlagFreq)
+		enc.writeByte(byte(freq))
+	}
+	enc.writeByte(typeByte)
+	enc.writeString(key)
+	enc.write(value)
+}
+
+// Close writes the end of the rdb and the checksum, and flushes the buffer.
+// It does not close the underlying writer.
+func (enc *Encoder) Close() {
+	enc.writeByte(kEOF)
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], enc.crc.Sum64())
+	_, err := enc.wr.Write(buf[:]) // not a part of the checksum
+	if err != nil {
+		log.Panicf("write rdb failed. error=[%v]", err)
+	}
+	enc.bytes += int64(len(buf))
+	if err = enc.wr.Flush(); err != nil {
+		log.Panicf("write rdb failed. error=[%v]", err)
+	}
+}
@@ -0,0 +1,57 @@
+package rdb
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+)
+
+func TestEncoderLoader(t *testing.T) {
+	defer func(opt config.ShakeOptions) { config.Opt = opt }(config.Opt)
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = 1024
+	config.Opt.Advanced.RDBParseWorkers = 1
+	config.Opt.Advanced.RDBRestoreCommandBehavior = "panic"
+	config.Opt.Target.Version = 7.2
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	list := listValue("a", "b", "c")
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, 11)
+	enc.WriteAux("redis-ver", "7.2.0")
+	enc.WriteAux("lua", "return 1")
+	enc.WriteFunction("#!lua name=lib\nredis.register_function('f', function() return 1 end)")
+	enc.WriteKey(0, "s", 0, []byte{3, 'a', 'b', 'c'}, expireAt, 0, 0)
+	enc.WriteKey(0, "l", 1, list, 0, 5, 3)
+	enc.WriteKey(3, "l", 1, list, 0, 0, 0)
+	enc.Close()
+	if enc.Bytes() != int64(buf.Len()) {
+		t.Errorf("got %d bytes, %d bytes are written", enc.Bytes(), buf.Len())
+	}
+
+	ch := make(chan *entry.Entry, 16)
+	ld := NewStreamLoader("test", nil, bytes.NewReader(buf.Bytes()), ch) // the checksum is verified
+	ld.ParseRDB()
+	close(ch)
+	var got [][]string
+	for e := range ch {
+		got = append(got, append([]string{strconv.Itoa(e.DbId)}, e.Argv...))
+	}
+	want := [][]string{
+		{"0", "script", "load", "return 1"},
+		{"0", "function", "load", "replace", "#!lua name=lib\nredis.register_function('f', function() return 1 end)"},
+		{"0", "restore", "s", strconv.FormatInt(expireAt, 10), createValueDump(0, []byte{3, 'a', 'b', 'c'}), "absttl"},
+		{"0", "restore", "l", "0", createValueDump(1, list), "idletime", "5", "freq", "3"},
+		{"3", "restore", "l", "0", createValueDump(1, list)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if ld.RDBBytes() != int64(buf.Len()) {
+		t.Errorf("got %d bytes parsed, want %d", ld.RDBBytes(), buf.Len())
+	}
+}
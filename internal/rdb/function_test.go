@@ -0,0 +1,50 @@
+package rdb
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"RedisShake/internal/entry"
+)
+
+// writeTestString writes s as an rdb string shorter than 16384 bytes.
+func writeTestString(buf *bytes.Buffer, s string) {
+	if len(s) < 64 {
+		buf.WriteByte(byte(len(s)))
+	} else {
+		buf.Write([]byte{0x40 | byte(len(s)>>8), byte(len(s))}) // 14 bits length
+	}
+	buf.WriteString(s)
+}
+
+func TestLoadFunction(t *testing.T) {
+	code := "#!lua name=mylib\nredis.register_function('f', function() return 1 end)"
+	var rdb bytes.Buffer
+	rdb.WriteString("REDIS0010")
+	rdb.WriteByte(kFlagFunction2)
+	writeTestString(&rdb, code)
+	// a function of 7.0 rc is skipped: name, engine, no description and code
+	rdb.WriteByte(kFlagFunction)
+	for _, s := range []string{"f", "LUA", "", "return 1"} {
+		writeTestString(&rdb, s)
+	}
+	rdb.WriteByte(kEOF)
+	rdb.WriteString(strings.Repeat("\x00", 8)) // checksum disabled
+
+	ch := make(chan *entry.Entry, 16)
+	NewStreamLoader("test", nil, &rdb, ch).ParseRDB()
+	close(ch)
+	var got []*entry.Entry
+	for e := range ch {
+		got = append(got, e)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got))
+	}
+	got[0].Parse()
+	if want := []string{"function", "load", "replace", code}; !reflect.DeepEqual(got[0].Argv, want) || got[0].CmdName != "FUNCTION-LOAD" {
+		t.Errorf("got %q of %s, want %q", got[0].Argv, got[0].CmdName, want)
+	}
+}
@@ -0,0 +1,133 @@
+package rdb
+
+import (
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/rdb/types"
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+const pipelineOutSize = 64 // buffered entries of a record
+
+// pipeline decodes the values on a pool of workers. parseRDBEntry frames the
+// rdb into records on one goroutine and the entries of the records are sent
+// to the channel of the loader in the order of the rdb, so the order of the
+// keys, the dbs and the other opcodes such as functions is kept.
+type pipeline struct {
+	ld    *Loader
+	jobs  chan *pipelineJob
+	order chan chan *entry.Entry // the out channel of each record, in the order of the rdb
+	wg    sync.WaitGroup
+}
+
+type pipelineJob struct {
+	rec *keyRecord
+	out chan *entry.Entry
+}
+
+// newPipeline returns nil if the values should be decoded in the loop of
+// parseRDBEntry, see rdb_parse_workers.
+func newPipeline(ld *Loader) *pipeline {
+	workers := config.Opt.Advanced.RDBParseWorkers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers <= 1 {
+		return nil
+	}
+	p := &pipeline{
+		ld:    ld,
+		jobs:  make(chan *pipelineJob, workers*4),
+		order: make(chan chan *entry.Entry, workers*4),
+	}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	p.wg.Add(1)
+	go p.emit()
+	return p
+}
+
+func (p *pipeline) work() {
+	for job := range p.jobs {
+		out := job.out
+		p.ld.parseValue(job.rec, func(e *entry.Entry) { out <- e })
+		close(out)
+	}
+}
+
+func (p *pipeline) emit() {
+	for out := range p.order {
+		for e := range out {
+			p.ld.ch <- e
+		}
+	}
+	p.wg.Done()
+}
+
+// submit queues the record to be decoded by the workers.
+func (p *pipeline) submit(rec *keyRecord) {
+	out := make(chan *entry.Entry, pipelineOutSize)
+	p.order <- out
+	p.jobs <- &pipelineJob{rec: rec, out: out}
+}
+
+// inline runs fn on the calling goroutine, the entries it sends follow the
+// entries of the records submitted before.
+func (p *pipeline) inline(fn func(out func(e *entry.Entry))) {
+	out := make(chan *entry.Entry, pipelineOutSize)
+	p.order <- out
+	fn(func(e *entry.Entry) { out <- e })
+	close(out)
+}
+
+// close waits until all the entries are sent.
+func (p *pipeline) close() {
+	close(p.jobs)
+	close(p.order)
+	p.wg.Wait()
+}
+
+// send sends an entry that is not a key, such as a function, in order.
+func (ld *Loader) send(e *entry.Entry) {
+	if ld.keyFunc != nil {
+		return // analyzing
+	}
+	if ld.pipeline == nil {
+		ld.ch <- e
+		return
+	}
+	ld.pipeline.inline(func(out func(e *entry.Entry)) { out(e) })
+}
+
+// dispatchValue decodes the value of the record in place, or frames it for
+// the workers of the pipeline. Values larger than target_redis_proto_max_bulk_len
+// and the types that can not be framed are decoded in place, so that they are
+// streamed instead of buffered.
+func (ld *Loader) dispatchValue(rd io.Reader, rec *keyRecord) {
+	if ld.keyFunc != nil {
+		ld.analyzeValue(rd, rec)
+		return
+	}
+	if ld.pipeline == nil {
+		rec.value = rd
+		ld.parseValue(rec, func(e *entry.Entry) { ld.ch <- e })
+		return
+	}
+	var raw bytes.Buffer
+	limit := config.Opt.Advanced.TargetRedisProtoMaxBulkLen
+	tooLarge := func() bool { return uint64(raw.Len()) > limit }
+	if types.SkipObject(io.TeeReader(rd, &raw), rec.typeByte, tooLarge) {
+		rec.value = &raw
+		ld.pipeline.submit(rec)
+		return
+	}
+	// decode the part that has been framed, then the rest
+	rec.value = io.MultiReader(&raw, rd)
+	ld.pipeline.inline(func(out func(e *entry.Entry)) {
+		ld.parseValue(rec, out)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"os"
 	"strconv"
@@ -34,18 +35,27 @@ type Loader struct {
 	replStreamDbId int // https://RedisShake/pull/430#issuecomment-1099014464
 
 	nowDBId  int
-	expireMs int64
+	expireAt int64 // absolute expire time in milliseconds, 0 if no expire
 	idle     int64
 	freq     int64
 
-	filPath string
-	fp      *os.File
+	filPath   string
+	src       io.Reader // set when parsing from a stream instead of a file
+	readBytes int64     // bytes read from the file or the stream
+	rdbBytes  int64     // bytes of the rdb parsed, including the checksum
 
-	ch         chan *entry.Entry
-	dumpBuffer bytes.Buffer
+	ch       chan *entry.Entry
+	pipeline *pipeline // nil if the values are decoded in the loop of parseRDBEntry
+
+	deferred types.DeferredCmds // sent after all the keys
 
 	name       string
 	updateFunc func(int64)
+
+	// set by NewAnalyzeLoader
+	auxFunc    func(key, value string)
+	keyFunc    func(info *KeyInfo)
+	analyzeErr error // see AnalyzeError
 }
 
 func NewLoader(name string, updateFunc func(int64), filPath string, ch chan *entry.Entry) *Loader {
@@ -57,24 +67,64 @@ func NewLoader(name string, updateFunc func(int64), filPath string, ch chan *ent
 	return ld
 }
 
+// NewStreamLoader is like NewLoader, but parses the rdb from src, e.g. the
+// replication socket, instead of a file. The caller owns src.
+func NewStreamLoader(name string, updateFunc func(int64), src io.Reader, ch chan *entry.Entry) *Loader {
+	ld := new(Loader)
+	ld.ch = ch
+	ld.src = src
+	ld.name = name
+	ld.updateFunc = updateFunc
+	return ld
+}
+
+// countingReader counts the bytes read from the underlying reader, for stat.
+// The parser only reaches the end of the reader if the rdb is truncated, so
+// io.EOF is replaced by an error that says so.
+type countingReader struct {
+	rd io.Reader
+	n  *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.rd.Read(p)
+	*c.n += int64(n)
+	if err == io.EOF {
+		err = fmt.Errorf("rdb is truncated after %d bytes", *c.n)
+	}
+	return n, err
+}
+
+// RDBBytes returns the size of the rdb parsed by ParseRDB. The bytes after it
+// may be read ahead from src, such as the commands following the rdb preamble
+// of an aof file, so the caller should seek src to it to read them.
+func (ld *Loader) RDBBytes() int64 {
+	return ld.rdbBytes
+}
+
 // ParseRDB parse rdb file
 // return repl stream db id
 func (ld *Loader) ParseRDB() int {
-	var err error
-	ld.fp, err = os.OpenFile(ld.filPath, os.O_RDONLY, 0666)
-	if err != nil {
-		log.Panicf("open file failed. file_path=[%s], error=[%s]", ld.filPath, err)
-	}
-	defer func() {
-		err = ld.fp.Close()
+	if ld.src == nil {
+		fp, err := os.OpenFile(ld.filPath, os.O_RDONLY, 0666)
 		if err != nil {
-			log.Panicf("close file failed. file_path=[%s], error=[%s]", ld.filPath, err)
+			log.Panicf("open file failed. file_path=[%s], error=[%s]", ld.filPath, err)
 		}
-	}()
-	rd := bufio.NewReader(ld.fp)
+		defer func() {
+			err = fp.Close()
+			if err != nil {
+				log.Panicf("close file failed. file_path=[%s], error=[%s]", ld.filPath, err)
+			}
+		}()
+		ld.src = fp
+	}
+	bufRd := bufio.NewReader(&countingReader{rd: ld.src, n: &ld.readBytes})
+	parsedRd := &countingReader{rd: bufRd, n: &ld.rdbBytes}
+	crc := utils.NewDigest()
+	rd := io.TeeReader(parsedRd, crc) // the checksum covers all the bytes before it
 	// magic + version
 	buf := make([]byte, 9)
-	_, err = io.ReadFull(rd, buf)
+	_, err := io.ReadFull(rd, buf)
 	if err != nil {
 		log.Panicf(err.Error())
 	}
@@ -88,22 +138,33 @@ func (ld *Loader) ParseRDB() int {
 	log.Debugf("[%s] RDB version: %d", ld.name, version)
 
 	// read entries
+	if ld.keyFunc == nil {
+		ld.pipeline = newPipeline(ld)
+	}
 	ld.parseRDBEntry(rd)
+	if ld.pipeline != nil {
+		ld.pipeline.close()
+	}
+	ld.deferred.Flush(func(dbId int, cmd types.RedisCmd) {
+		e := entry.NewEntry()
+		e.DbId = dbId
+		e.Argv = cmd
+		ld.ch <- e
+	})
+	if ld.analyzeErr == nil {
+		ld.checkCRC64(parsedRd, version, crc.Sum64())
+	}
 
 	return ld.replStreamDbId
 }
 
-func (ld *Loader) parseRDBEntry(rd *bufio.Reader) {
+func (ld *Loader) parseRDBEntry(rd io.Reader) {
 	// for stat
 	updateProcessSize := func() {
 		if ld.updateFunc == nil {
 			return
 		}
-		offset, err := ld.fp.Seek(0, io.SeekCurrent)
-		if err != nil {
-			log.Panicf(err.Error())
-		}
-		ld.updateFunc(offset)
+		ld.updateFunc(ld.readBytes)
 	}
 	defer updateProcessSize()
 
@@ -119,6 +180,9 @@ func (ld *Loader) parseRDBEntry(rd *bufio.Reader) {
 		case kFlagAUX:
 			key := structure.ReadString(rd)
 			value := structure.ReadString(rd)
+			if ld.auxFunc != nil {
+				ld.auxFunc(key, value)
+			}
 			if key == "repl-stream-db" {
 				var err error
 				ld.replStreamDbId, err = strconv.Atoi(value)
@@ -129,68 +193,60 @@ func (ld *Loader) parseRDBEntry(rd *bufio.Reader) {
 			} else if key == "lua" {
 				e := entry.NewEntry()
 				e.Argv = []string{"script", "load", value}
-				ld.ch <- e
+				ld.send(e)
 				log.Debugf("[%s] LUA script: [%s]", ld.name, value)
 			} else {
 				log.Debugf("[%s] RDB AUX: key=[%s], value=[%s]", ld.name, key, value)
 			}
+		case kFlagFunction2:
+			code := structure.ReadString(rd)
+			e := entry.NewEntry()
+			e.Argv = []string{"function", "load", "replace", code}
+			ld.send(e)
+			log.Debugf("[%s] function library: [%s]", ld.name, code)
+		case kFlagFunction:
+			// functions of 7.0 rc1 and rc2 are not libraries, they can not
+			// be loaded by FUNCTION LOAD of Redis 7.0 GA and later
+			name := structure.ReadString(rd)
+			engine := structure.ReadString(rd)
+			if structure.ReadLength(rd) != 0 { // has description
+				_ = structure.ReadString(rd)
+			}
+			_ = structure.ReadString(rd) // code
+			log.Warnf("[%s] skip function of Redis 7.0 release candidate, please recreate it manually. name=[%s], engine=[%s]", ld.name, name, engine)
+		case kFlagModuleAux:
+			cmds := types.ParseModuleAux(rd)
+			for _, cmd := range cmds {
+				e := entry.NewEntry()
+				e.Argv = cmd
+				ld.send(e)
+			}
 		case kFlagResizeDB:
 			dbSize := structure.ReadLength(rd)
 			expireSize := structure.ReadLength(rd)
 			log.Debugf("[%s] RDB resize db: db_size=[%d], expire_size=[%d]", ld.name, dbSize, expireSize)
 		case kFlagExpireMs:
-			ld.expireMs = int64(structure.ReadUint64(rd)) - time.Now().UnixMilli()
-			if ld.expireMs < 0 {
-				ld.expireMs = 1
-			}
+			ld.expireAt = int64(structure.ReadUint64(rd))
 		case kFlagExpire:
-			ld.expireMs = int64(structure.ReadUint32(rd))*1000 - time.Now().UnixMilli()
-			if ld.expireMs < 0 {
-				ld.expireMs = 1
-			}
+			ld.expireAt = int64(structure.ReadUint32(rd)) * 1000
 		case kFlagSelect:
 			ld.nowDBId = int(structure.ReadLength(rd))
 		case kEOF:
 			return
 		default:
-			key := structure.ReadString(rd)
-			var value bytes.Buffer
-			anotherReader := io.TeeReader(rd, &value)
-			o := types.ParseObject(anotherReader, typeByte, key)
-			if uint64(value.Len()) > config.Opt.Advanced.TargetRedisProtoMaxBulkLen {
-				cmds := o.Rewrite()
-				for _, cmd := range cmds {
-					e := entry.NewEntry()
-					e.DbId = ld.nowDBId
-					e.Argv = cmd
-					ld.ch <- e
-				}
-				if ld.expireMs != 0 {
-					e := entry.NewEntry()
-					e.DbId = ld.nowDBId
-					e.Argv = []string{"PEXPIRE", key, strconv.FormatInt(ld.expireMs, 10)}
-					ld.ch <- e
-				}
-			} else {
-				e := entry.NewEntry()
-				e.DbId = ld.nowDBId
-				v := ld.createValueDump(typeByte, value.Bytes())
-				e.Argv = []string{"restore", key, strconv.FormatInt(ld.expireMs, 10), v}
-				if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
-					//if config.Opt.Target.Version < 3.0 {
-					//	log.Panicf("RDB restore command behavior is rewrite, but target redis version is %f, not support REPLACE modifier", config.Config.Target.Version)
-					//}
-					e.Argv = append(e.Argv, "replace")
-				}
-				//if ld.idle != 0 && config.Config.Target.Version >= 5.0 {
-				//	e.Argv = append(e.Argv, "idletime", strconv.FormatInt(ld.idle, 10))
-				//}
-				//if ld.freq != 0 && config.Config.Target.Version >= 5.0 {
-				//	e.Argv = append(e.Argv, "freq", strconv.FormatInt(ld.freq, 10))
-				//}
-				ld.ch <- e
+			rec := &keyRecord{
+				dbId:     ld.nowDBId,
+				key:      structure.ReadString(rd),
+				typeByte: typeByte,
+				expireAt: ld.expireAt,
+				idle:     ld.idle,
+				freq:     ld.freq,
+			}
+			ld.dispatchValue(rd, rec)
+			if ld.analyzeErr != nil {
+				return
 			}
-			ld.expireMs = 0
+			ld.expireAt = 0
 			ld.idle = 0
 			ld.freq = 0
 		}
@@ -202,13 +258,210 @@ func (ld *Loader) parseRDBEntry(rd *bufio.Reader) {
 	}
 }
 
-func (ld *Loader) createValueDump(typeByte byte, val []byte) string {
-	ld.dumpBuffer.Reset()
-	_, _ = ld.dumpBuffer.Write([]byte{typeByte})
-	_, _ = ld.dumpBuffer.Write(val)
-	_ = binary.Write(&ld.dumpBuffer, binary.LittleEndian, uint16(6))
+// checkCRC64 compares sum with the CRC64 that follows the EOF opcode since RDB
+// version 5, which is 0 if rdbchecksum of the source is disabled.
+func (ld *Loader) checkCRC64(rd io.Reader, version int, sum uint64) {
+	if version < 5 {
+		return
+	}
+	buf := make([]byte, 8)
+	_, err := io.ReadFull(rd, buf)
+	if err != nil {
+		log.Panicf("[%s] read rdb checksum failed. error=[%v]", ld.name, err)
+	}
+	expected := binary.LittleEndian.Uint64(buf)
+	if expected == 0 {
+		log.Debugf("[%s] rdb checksum is disabled", ld.name)
+		return
+	}
+	if expected != sum {
+		log.Panicf("[%s] rdb checksum mismatch, the rdb is corrupted. expected=[%016x], actual=[%016x]", ld.name, expected, sum)
+	}
+	log.Debugf("[%s] rdb checksum verified: [%016x]", ld.name, sum)
+}
+
+// keyRecord is a key read from the rdb and the state of the opcodes before it.
+type keyRecord struct {
+	dbId     int
+	key      string
+	typeByte byte
+	expireAt int64 // absolute expire time in milliseconds, 0 if no expire
+	idle     int64
+	freq     int64
+	value    io.Reader // the value that is not read yet
+}
+
+// parseValue sends RESTORE of the value, or the rewrite commands of
+// types.RewriteIterator if the target can not RESTORE it. The value is framed
+// without decoding first, and the iterator is only built if the value is
+// larger than target_redis_proto_max_bulk_len, then the commands are sent as
+// soon as they are decoded. Module values and the values that can not be
+// framed are decoded by the iterator while the raw value is kept, and the
+// commands decoded are pending until the value is known to be too large.
+func (ld *Loader) parseValue(rec *keyRecord, out func(e *entry.Entry)) {
+	drop := rec.expireAt != 0 && rec.expireAt <= time.Now().UnixMilli() && config.Opt.Advanced.DropExpiredKeys
+	// the target can not RESTORE types newer than its RDB version
+	rewrite := config.Opt.Advanced.ForceRewrite || !types.TargetSupportsType(rec.typeByte)
+	if !rewrite && !types.IsModuleType(rec.typeByte) {
+		raw, framed := rec.value.(*bytes.Buffer) // framed by dispatchValue
+		if !framed {
+			raw = new(bytes.Buffer)
+			limit := config.Opt.Advanced.TargetRedisProtoMaxBulkLen
+			tooLarge := func() bool { return uint64(raw.Len()) > limit }
+			framed = types.SkipObject(io.TeeReader(rec.value, raw), rec.typeByte, tooLarge)
+			rewrite = tooLarge()
+			rec.value = io.MultiReader(raw, rec.value) // decode the part read, then the rest
+		}
+		if framed {
+			if drop {
+				log.Debugf("[%s] drop expired key. key=[%s], expire_at=[%d]", ld.name, rec.key, rec.expireAt)
+			} else {
+				out(ld.restoreEntry(rec, raw.Bytes()))
+			}
+			return
+		}
+	}
+
+	value := &valueBuffer{limit: config.Opt.Advanced.TargetRedisProtoMaxBulkLen, overflow: rewrite}
+	it := types.NewRewriteIterator(io.TeeReader(rec.value, value), rec.typeByte, rec.key)
+	if it == nil {
+		return // skipped
+	}
+	started := false
+	send := func(cmds []types.RedisCmd) {
+		if !started && config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
+			// the rewrite commands add to the existing value
+			cmds = append([]types.RedisCmd{{"DEL", rec.key}}, cmds...)
+		}
+		started = true
+		for _, cmd := range cmds {
+			if ld.deferred.Add(rec.dbId, cmd) {
+				continue
+			}
+			e := entry.NewEntry()
+			e.DbId = rec.dbId
+			e.Argv = cmd
+			out(e)
+		}
+	}
+	var pending []types.RedisCmd
+	for {
+		if !value.overflow && it.Consumed() {
+			break // restored, the commands are not needed
+		}
+		cmds := it.Next()
+		if cmds == nil {
+			break
+		}
+		if drop {
+			continue
+		}
+		if !value.overflow {
+			pending = append(pending, cmds...)
+			continue
+		}
+		if pending != nil {
+			send(pending)
+			pending = nil
+		}
+		send(cmds)
+	}
+
+	if drop {
+		log.Debugf("[%s] drop expired key. key=[%s], expire_at=[%d]", ld.name, rec.key, rec.expireAt)
+	} else if err := it.Err(); err != nil && value.overflow {
+		types.SkipUnrewritableKey(rec.key, err)
+	} else if value.overflow {
+		send(pending) // overflowed while reading the end of the value
+		if rec.expireAt != 0 {
+			send([]types.RedisCmd{{"PEXPIREAT", rec.key, strconv.FormatInt(rec.expireAt, 10)}})
+		}
+	} else {
+		out(ld.restoreEntry(rec, value.buf.Bytes()))
+	}
+}
+
+// restoreEntry returns RESTORE of the raw value.
+func (ld *Loader) restoreEntry(rec *keyRecord, value []byte) *entry.Entry {
+	e := entry.NewEntry()
+	e.DbId = rec.dbId
+	v := createValueDump(rec.typeByte, value)
+	e.Argv = []string{"restore", rec.key, restoreTTL(rec.expireAt), v}
+	if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
+		//if config.Opt.Target.Version < 3.0 {
+		//	log.Panicf("RDB restore command behavior is rewrite, but target redis version is %f, not support REPLACE modifier", config.Config.Target.Version)
+		//}
+		e.Argv = append(e.Argv, "replace")
+	}
+	if rec.expireAt != 0 && config.Opt.Target.Version >= 5.0 {
+		e.Argv = append(e.Argv, "absttl")
+	}
+	if rec.idle != 0 && config.Opt.Target.Version >= 5.0 {
+		e.Argv = append(e.Argv, "idletime", strconv.FormatInt(rec.idle, 10))
+	}
+	if rec.freq != 0 && config.Opt.Target.Version >= 5.0 {
+		e.Argv = append(e.Argv, "freq", strconv.FormatInt(rec.freq, 10))
+	}
+	return e
+}
+
+// valueBuffer keeps the raw value written to it until it grows larger than
+// limit, then the value can only be rewritten and the buffer is released.
+type valueBuffer struct {
+	buf      bytes.Buffer
+	limit    uint64
+	overflow bool
+}
+
+func (b *valueBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if uint64(b.buf.Len()+len(p)) > b.limit {
+		b.overflow = true
+		b.buf = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// restoreTTL returns the ttl argument of RESTORE. It is the absolute expire
+// time if the target supports ABSTTL, so it does not depend on the clock of
+// redis-shake and the time the entry waits in the pipeline.
+func restoreTTL(expireAt int64) string {
+	if expireAt == 0 || config.Opt.Target.Version >= 5.0 {
+		return strconv.FormatInt(expireAt, 10)
+	}
+	ttl := expireAt - time.Now().UnixMilli()
+	if ttl <= 0 {
+		ttl = 1
+	}
+	return strconv.FormatInt(ttl, 10)
+}
+
+func createValueDump(typeByte byte, val []byte) string {
+	var dumpBuffer bytes.Buffer
+	dumpBuffer.Grow(len(val) + 11)
+	_, _ = dumpBuffer.Write([]byte{typeByte})
+	_, _ = dumpBuffer.Write(val)
+	_ = binary.Write(&dumpBuffer, binary.LittleEndian, uint16(6))
 	// calc crc
-	sum64 := utils.CalcCRC64(ld.dumpBuffer.Bytes())
-	_ = binary.Write(&ld.dumpBuffer, binary.LittleEndian, sum64)
-	return ld.dumpBuffer.String()
+	sum64 := utils.CalcCRC64(dumpBuffer.Bytes())
+	_ = binary.Write(&dumpBuffer, binary.LittleEndian, sum64)
+	return dumpBuffer.String()
+}
+
+// ParseValueDump splits the payload of DUMP or RESTORE into the type and the
+// value, it is the reverse of createValueDump. The checksum is verified
+// unless it is 0, which means that it is disabled.
+func ParseValueDump(dump string) (typeByte byte, value []byte, err error) {
+	if len(dump) < 11 {
+		return 0, nil, fmt.Errorf("dump payload is too short, len=[%d]", len(dump))
+	}
+	body := []byte(dump[:len(dump)-8])
+	expected := binary.LittleEndian.Uint64([]byte(dump[len(dump)-8:]))
+	if expected != 0 && expected != utils.CalcCRC64(body) {
+		return 0, nil, fmt.Errorf("dump payload checksum mismatch")
+	}
+	return body[0], body[1 : len(body)-2], nil // 2 bytes of rdb version
 }
@@ -0,0 +1,90 @@
+package rdb
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+)
+
+// listValue returns a list of RDB_TYPE_LIST with the short elements.
+func listValue(elements ...string) []byte {
+	buf := []byte{byte(len(elements))}
+	for _, element := range elements {
+		buf = append(buf, byte(len(element)))
+		buf = append(buf, element...)
+	}
+	return buf
+}
+
+func parseTestValue(rec *keyRecord) [][]string {
+	var argvs [][]string
+	ld := NewLoader("test", nil, "", nil)
+	ld.parseValue(rec, func(e *entry.Entry) { argvs = append(argvs, e.Argv) })
+	return argvs
+}
+
+func TestParseValueRewrite(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = 4
+	config.Opt.Advanced.RewriteBatchCount = 2
+	config.Opt.Advanced.RewriteBatchBytes = 100
+	config.Opt.Advanced.RDBRestoreCommandBehavior = "rewrite"
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	got := parseTestValue(&keyRecord{
+		key:      "l",
+		typeByte: 1, // list
+		expireAt: expireAt,
+		value:    bytes.NewReader(listValue("a", "b", "c")),
+	})
+	want := [][]string{
+		{"DEL", "l"},
+		{"rpush", "l", "a", "b"},
+		{"rpush", "l", "c"},
+		{"PEXPIREAT", "l", strconv.FormatInt(expireAt, 10)}, // after the last batch
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseValueRestore(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = 1024
+	config.Opt.Advanced.RDBRestoreCommandBehavior = "panic"
+
+	value := listValue("a", "b", "c")
+	got := parseTestValue(&keyRecord{
+		key:      "l",
+		typeByte: 1,
+		value:    bytes.NewReader(value),
+	})
+	want := [][]string{{"restore", "l", "0", createValueDump(1, value)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseValueRestoreWithoutDecoding(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = 1024
+	config.Opt.Advanced.RDBRestoreCommandBehavior = "panic"
+
+	// a ziplist that can not be decoded, it is restored as it is since the
+	// iterator is only built when the key is rewritten
+	value := append([]byte{13}, "not a ziplist"...)
+	got := parseTestValue(&keyRecord{
+		key:      "l",
+		typeByte: 10, // list ziplist
+		value:    bytes.NewReader(value),
+	})
+	want := [][]string{{"restore", "l", "0", createValueDump(10, value)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
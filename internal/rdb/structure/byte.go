@@ -18,3 +18,11 @@ func ReadBytes(rd io.Reader, n int) []byte {
 	}
 	return buf
 }
+
+// SkipBytes reads n bytes and discards them.
+func SkipBytes(rd io.Reader, n int64) {
+	_, err := io.CopyN(io.Discard, rd, n)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"strconv"
 )
 
@@ -69,3 +70,44 @@ func ReadModuleEof(rd io.Reader) error {
 	return nil
 
 }
+
+// ModuleValue is a value of the module2 opcode stream.
+type ModuleValue struct {
+	Opcode uint64
+	Uint   uint64  // rdbModuleOpcodeSINT and rdbModuleOpcodeUINT, cast to int64 for SINT
+	Double float64 // rdbModuleOpcodeFLOAT and rdbModuleOpcodeDOUBLE
+	String string  // rdbModuleOpcodeSTRING
+}
+
+func (v ModuleValue) IsUnsigned() bool { return v.Opcode == rdbModuleOpcodeUINT }
+func (v ModuleValue) IsSigned() bool   { return v.Opcode == rdbModuleOpcodeSINT }
+func (v ModuleValue) IsDouble() bool {
+	return v.Opcode == rdbModuleOpcodeDOUBLE || v.Opcode == rdbModuleOpcodeFLOAT
+}
+func (v ModuleValue) IsString() bool { return v.Opcode == rdbModuleOpcodeSTRING }
+
+// ReadModuleValues reads the values of a module2 value or module aux data
+// until the EOF opcode, without knowing the module. It is how redis skips the
+// data of modules that are not loaded.
+func ReadModuleValues(rd io.Reader) []ModuleValue {
+	var values []ModuleValue
+	for {
+		opcode := ReadLength(rd)
+		v := ModuleValue{Opcode: opcode}
+		switch opcode {
+		case rdbModuleOpcodeEOF:
+			return values
+		case rdbModuleOpcodeSINT, rdbModuleOpcodeUINT:
+			v.Uint = ReadLength(rd)
+		case rdbModuleOpcodeFLOAT:
+			v.Double = float64(math.Float32frombits(ReadUint32(rd)))
+		case rdbModuleOpcodeDOUBLE:
+			v.Double = ReadDouble(rd)
+		case rdbModuleOpcodeSTRING:
+			v.String = ReadString(rd)
+		default:
+			log.Panicf("unknown module opcode: %d", opcode)
+		}
+		values = append(values, v)
+	}
+}
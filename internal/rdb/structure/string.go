@@ -42,6 +42,32 @@ func ReadString(rd io.Reader) string {
 	return string(ReadBytes(rd, int(length)))
 }
 
+// SkipString reads a string without decoding it.
+func SkipString(rd io.Reader) {
+	length, special, err := readEncodedLength(rd)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	if special {
+		switch length {
+		case RDBEncInt8:
+			SkipBytes(rd, 1)
+		case RDBEncInt16:
+			SkipBytes(rd, 2)
+		case RDBEncInt32:
+			SkipBytes(rd, 4)
+		case RDBEncLZF:
+			inLen := ReadLength(rd)
+			_ = ReadLength(rd) // outLen
+			SkipBytes(rd, int64(inLen))
+		default:
+			log.Panicf("Unknown string encode type %d", length)
+		}
+		return
+	}
+	SkipBytes(rd, int64(length))
+}
+
 func lzfDecompress(in []byte, outLen int) string {
 	out := make([]byte, outLen)
 
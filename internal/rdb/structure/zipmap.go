@@ -0,0 +1,57 @@
+package structure
+
+import (
+	"RedisShake/internal/log"
+	"bufio"
+	"io"
+	"strings"
+)
+
+const (
+	zipmapBigLen = 254 // the length is in the following 4 bytes
+	zipmapEnd    = 255
+)
+
+// ReadZipmap reads a zipmap, the encoding of small hashes before Redis 2.6.
+// It returns the keys and values as [key1, value1, key2, value2, ...].
+func ReadZipmap(rd io.Reader) []string {
+	rd = bufio.NewReader(strings.NewReader(ReadString(rd)))
+
+	// The general layout of the zipmap is as follows:
+	// <zmlen><len>"foo"<len><free>"bar"<len>"hello"<len><free>"world"<end>
+	// zmlen is the number of entries only if it is less than 254, so it is
+	// ignored and the entries are read until <end>.
+	_ = ReadByte(rd) // zmlen
+	var elements []string
+	for {
+		length, end := readZipmapLength(rd)
+		if end {
+			break
+		}
+		key := string(ReadBytes(rd, length))
+		length, end = readZipmapLength(rd)
+		if end {
+			log.Panicf("invalid zipmap, no value for key. key=[%s]", key)
+		}
+		free := int(ReadByte(rd))
+		value := string(ReadBytes(rd, length))
+		_ = ReadBytes(rd, free) // unused bytes after the value
+		elements = append(elements, key, value)
+	}
+	return elements
+}
+
+// readZipmapLength reads the length of a key or value. The length is 1 byte if
+// it is less than 254, otherwise 254 followed by 4 bytes unsigned little endian
+// length. 255 means the end of the zipmap.
+func readZipmapLength(rd io.Reader) (length int, end bool) {
+	b := ReadByte(rd)
+	switch b {
+	case zipmapEnd:
+		return 0, true
+	case zipmapBigLen:
+		return int(ReadUint32(rd)), false
+	default:
+		return int(b), false
+	}
+}
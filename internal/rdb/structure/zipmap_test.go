@@ -0,0 +1,61 @@
+package structure
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// rdbString encodes s as a RDB string without compression.
+func rdbString(s string) []byte {
+	n := len(s)
+	if n < 64 {
+		return append([]byte{byte(n)}, s...)
+	}
+	return append([]byte{0x40 | byte(n>>8), byte(n)}, s...)
+}
+
+func testZipmap(t *testing.T, name string, zipmap string, expected []string) {
+	elements := ReadZipmap(bytes.NewReader(rdbString(zipmap)))
+	if len(elements) != len(expected) {
+		t.Fatalf("%s: ReadZipmap() = %q, expected %q", name, elements, expected)
+	}
+	for i := range elements {
+		if elements[i] != expected[i] {
+			t.Fatalf("%s: ReadZipmap() = %q, expected %q", name, elements, expected)
+		}
+	}
+}
+
+func TestReadZipmap(t *testing.T) {
+	// the example in zipmap.c of Redis 2.x: "foo" => "bar", "hello" => "world"
+	testZipmap(t, "example",
+		"\x02\x03foo\x03\x00bar\x05hello\x05\x00world\xff",
+		[]string{"foo", "bar", "hello", "world"})
+
+	// empty zipmap
+	testZipmap(t, "empty", "\x00\xff", nil)
+
+	// free bytes after the value, left by updating "foo" from "barbaz" to "bar"
+	testZipmap(t, "free",
+		"\x01\x03foo\x03\x03barXXX\xff",
+		[]string{"foo", "bar"})
+
+	// value of 300 bytes, the length is 254 followed by 4 bytes little endian
+	long := strings.Repeat("v", 300)
+	testZipmap(t, "big length",
+		"\x02\x01k\xfe\x2c\x01\x00\x00\x00"+long+"\x02k2\x01\x00v\xff",
+		[]string{"k", long, "k2", "v"})
+
+	// zmlen is 254 when the zipmap has 254 entries or more, it is not the count
+	var sb strings.Builder
+	var expected []string
+	sb.WriteString("\xfe")
+	for i := 0; i < 300; i++ {
+		key := string(rune('a'+i%26)) + strings.Repeat("x", i/26)
+		sb.WriteString(string([]byte{byte(len(key))}) + key + "\x01\x00v")
+		expected = append(expected, key, "v")
+	}
+	sb.WriteString("\xff")
+	testZipmap(t, "zmlen 254", sb.String(), expected)
+}
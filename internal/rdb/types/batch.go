@@ -0,0 +1,68 @@
+package types
+
+import (
+	"RedisShake/internal/config"
+	"strings"
+)
+
+// batchableCmds are the rewrite commands that can take more elements.
+var batchableCmds = map[string]bool{
+	"rpush": true, // RPUSH key element [element ...]
+	"sadd":  true, // SADD key member [member ...]
+	"zadd":  true, // ZADD key score member [score member ...]
+	"hset":  true, // HSET key field value [field value ...]
+}
+
+// batchCmds merges the consecutive commands that add elements to the same key
+// into one, such as SADD k m1 and SADD k m2 into SADD k m1 m2. A command is
+// limited by rewrite_batch_count elements and rewrite_batch_bytes bytes.
+// Other commands, such as HPEXPIREAT of a field, are moved after the merged
+// command they follow.
+func batchCmds(cmds []RedisCmd) []RedisCmd {
+	maxCount := config.Opt.Advanced.RewriteBatchCount
+	maxBytes := config.Opt.Advanced.RewriteBatchBytes
+	if maxCount <= 1 {
+		return cmds
+	}
+
+	var ret []RedisCmd
+	var cur RedisCmd
+	var curCount, curBytes uint64
+	var deferred []RedisCmd
+	flush := func() {
+		if cur != nil {
+			ret = append(ret, cur)
+			cur = nil
+		}
+		ret = append(ret, deferred...)
+		deferred = nil
+	}
+	for _, cmd := range cmds {
+		if !batchableCmds[strings.ToLower(cmd[0])] || len(cmd) < 2 {
+			if cur != nil && len(cmd) >= 2 && cmd[1] == cur[1] {
+				deferred = append(deferred, cmd)
+			} else {
+				flush()
+				ret = append(ret, cmd)
+			}
+			continue
+		}
+		var size uint64
+		for _, arg := range cmd[2:] {
+			size += uint64(len(arg))
+		}
+		if cur != nil && strings.EqualFold(cmd[0], cur[0]) && cmd[1] == cur[1] &&
+			curCount < maxCount && curBytes+size <= maxBytes {
+			cur = append(cur, cmd[2:]...)
+			curCount++
+			curBytes += size
+			continue
+		}
+		flush()
+		cur = append(RedisCmd{}, cmd...)
+		curCount = 1
+		curBytes = size
+	}
+	flush()
+	return ret
+}
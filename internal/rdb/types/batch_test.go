@@ -0,0 +1,104 @@
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"RedisShake/internal/config"
+)
+
+func TestBatchCmds(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	large := strings.Repeat("x", 10)
+	tests := []struct {
+		name     string
+		maxCount uint64
+		maxBytes uint64
+		cmds     []RedisCmd
+		want     []RedisCmd
+	}{
+		{
+			name:     "disabled",
+			maxCount: 1,
+			maxBytes: 100,
+			cmds:     []RedisCmd{{"sadd", "s", "a"}, {"sadd", "s", "b"}},
+			want:     []RedisCmd{{"sadd", "s", "a"}, {"sadd", "s", "b"}},
+		},
+		{
+			name:     "split by count",
+			maxCount: 2,
+			maxBytes: 100,
+			cmds:     []RedisCmd{{"rpush", "l", "a"}, {"rpush", "l", "b"}, {"rpush", "l", "c"}, {"rpush", "l", "d"}, {"rpush", "l", "e"}},
+			want:     []RedisCmd{{"rpush", "l", "a", "b"}, {"rpush", "l", "c", "d"}, {"rpush", "l", "e"}},
+		},
+		{
+			name:     "split by bytes",
+			maxCount: 100,
+			maxBytes: 4, // score and member
+			cmds:     []RedisCmd{{"zadd", "z", "1", "a"}, {"zadd", "z", "2", "b"}, {"zadd", "z", "3", "c"}},
+			want:     []RedisCmd{{"zadd", "z", "1", "a", "2", "b"}, {"zadd", "z", "3", "c"}},
+		},
+		{
+			name:     "oversized element",
+			maxCount: 100,
+			maxBytes: 4,
+			cmds:     []RedisCmd{{"sadd", "s", "a"}, {"sadd", "s", large}, {"sadd", "s", "b"}, {"sadd", "s", "c"}},
+			want:     []RedisCmd{{"sadd", "s", "a"}, {"sadd", "s", large}, {"sadd", "s", "b", "c"}},
+		},
+		{
+			name:     "different keys and commands",
+			maxCount: 100,
+			maxBytes: 100,
+			cmds:     []RedisCmd{{"sadd", "s1", "a"}, {"sadd", "s2", "b"}, {"SADD", "s2", "c"}, {"set", "k", "v"}, {"sadd", "s2", "d"}},
+			want:     []RedisCmd{{"sadd", "s1", "a"}, {"sadd", "s2", "b", "c"}, {"set", "k", "v"}, {"sadd", "s2", "d"}},
+		},
+		{
+			name:     "field ttl after the batch of the field",
+			maxCount: 2,
+			maxBytes: 100,
+			cmds: []RedisCmd{
+				{"hset", "h", "f1", "v1"}, {"hpexpireat", "h", "1000", "fields", "1", "f1"},
+				{"hset", "h", "f2", "v2"}, {"hpexpireat", "h", "2000", "fields", "1", "f2"},
+				{"hset", "h", "f3", "v3"}, {"hpexpireat", "h", "3000", "fields", "1", "f3"},
+			},
+			want: []RedisCmd{
+				{"hset", "h", "f1", "v1", "f2", "v2"},
+				{"hpexpireat", "h", "1000", "fields", "1", "f1"}, {"hpexpireat", "h", "2000", "fields", "1", "f2"},
+				{"hset", "h", "f3", "v3"},
+				{"hpexpireat", "h", "3000", "fields", "1", "f3"},
+			},
+		},
+	}
+	for _, test := range tests {
+		config.Opt.Advanced.RewriteBatchCount = test.maxCount
+		config.Opt.Advanced.RewriteBatchBytes = test.maxBytes
+		if got := batchCmds(test.cmds); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestElementIteratorBatchCount(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	config.Opt.Advanced.RewriteBatchCount = rewriteBatchSize * 2
+	config.Opt.Advanced.RewriteBatchBytes = 1 << 20
+
+	// a batch reads rewrite_batch_count elements if it is larger, and
+	// they are merged into one command
+	w := new(valueWriter)
+	n := rewriteBatchSize*2 + 3
+	w.length(uint64(n))
+	for i := 0; i < n; i++ {
+		w.rdbString("m" + strconv.Itoa(i))
+	}
+	batches := drain(NewRewriteIterator(bytes.NewReader(w.Bytes()), rdbTypeSet, "s"))
+	if len(batches) != 2 || len(batches[0]) != 1 || len(batches[1]) != 1 {
+		t.Fatalf("got %d batches", len(batches))
+	}
+	if len(batches[0][0]) != 2+rewriteBatchSize*2 || len(batches[1][0]) != 2+3 {
+		t.Errorf("got SADD of %d and %d args", len(batches[0][0]), len(batches[1][0]))
+	}
+}
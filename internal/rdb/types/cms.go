@@ -0,0 +1,49 @@
+package types
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"RedisShake/internal/rdb/structure"
+)
+
+// CMSObject for CMSk-TYPE at https://github.com/RedisBloom/RedisBloom
+//
+// There is no command to load the counters of a Count-Min Sketch, so only an
+// empty sketch can be rewritten, by CMS.INITBYDIM with the same dimensions.
+type CMSObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+func (o *CMSObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+func (o *CMSObject) Rewrite() []RedisCmd {
+	c := &moduleValueCursor{values: o.values}
+	width := c.unsigned()
+	depth := c.unsigned()
+	count := c.unsigned()
+	_ = c.string() // counters
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(o.values)-c.pos)
+	}
+	if c.err != nil {
+		o.err = fmt.Errorf("can not decode the value of Count-Min Sketch, encver=[%d]: %w", o.encver, c.err)
+		return nil
+	}
+	if count != 0 {
+		o.err = fmt.Errorf("the counters of Count-Min Sketch can not be written by commands, count=[%d]", count)
+		return nil
+	}
+	return []RedisCmd{{"CMS.INITBYDIM", o.key, strconv.FormatUint(width, 10), strconv.FormatUint(depth, 10)}}
+}
+
+func (o *CMSObject) RewriteError() error {
+	return o.err
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCMSObject(t *testing.T) {
+	save := func(count uint64) func(w *valueWriter) {
+		return func(w *valueWriter) {
+			w.unsigned(2000) // width
+			w.unsigned(5)    // depth
+			w.unsigned(count)
+			w.string(string(make([]byte, 2000*5*4)))
+		}
+	}
+	it := parseModule(t, "CMSk-TYPE", 0, "k", save(0))
+	want := []RedisCmd{{"CMS.INITBYDIM", "k", "2000", "5"}}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+
+	// the counters can not be written
+	it = parseModule(t, "CMSk-TYPE", 0, "k", save(3))
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+
+	it = parseModule(t, "CMSk-TYPE", 0, "k", func(w *valueWriter) {
+		w.unsigned(2000)
+		w.unsigned(5)
+		w.unsigned(0)
+	})
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+}
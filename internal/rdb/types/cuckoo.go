@@ -0,0 +1,118 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"RedisShake/internal/config"
+	"RedisShake/internal/rdb/structure"
+)
+
+const (
+	CF_MIN_EXPANSION_ENC = 4 // bucket size, max iterations and expansion are saved since this version
+
+	CF_DEFAULT_BUCKET_SIZE    = 2
+	CF_DEFAULT_MAX_ITERATIONS = 20
+	CF_DEFAULT_EXPANSION      = 1
+)
+
+// CuckooObject for MBbloomCF at https://github.com/RedisBloom/RedisBloom
+type CuckooObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+type cuckooFilter struct {
+	numFilters    uint64
+	numBuckets    uint64
+	numItems      uint64
+	numDeletes    uint64
+	bucketSize    uint64
+	maxIterations uint64
+	expansion     uint64
+	filters       []string
+}
+
+func (o *CuckooObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+func (o *CuckooObject) Rewrite() []RedisCmd {
+	cf, err := o.decode()
+	if err != nil {
+		o.err = fmt.Errorf("can not decode the value of cuckoo filter, encver=[%d]: %w", o.encver, err)
+		return nil
+	}
+	// the header of CF.SCANDUMP has bucket size, max iterations and
+	// expansion since RedisBloom 2.2
+	var withExpansion bool
+	if ver := config.Opt.Module.TargetMBbloomVersion; ver >= 20200 {
+		withExpansion = true
+	} else if ver >= 10000 {
+		withExpansion = false
+	} else {
+		withExpansion = o.encver >= CF_MIN_EXPANSION_ENC
+	}
+	cs := []RedisCmd{{"CF.LOADCHUNK", o.key, "1", getEncodedCuckooHeader(cf, withExpansion)}}
+	curIter := uint64(1)
+	for _, data := range cf.filters {
+		for off := uint64(0); off < uint64(len(data)); {
+			l := uint64(len(data)) - off
+			if l > MAX_SCANDUMP_SIZE {
+				l = MAX_SCANDUMP_SIZE
+			}
+			curIter += l
+			cs = append(cs, RedisCmd{"CF.LOADCHUNK", o.key, strconv.FormatUint(curIter, 10), data[off : off+l]})
+			off += l
+		}
+	}
+	return cs
+}
+
+func (o *CuckooObject) RewriteError() error {
+	return o.err
+}
+
+func (o *CuckooObject) decode() (*cuckooFilter, error) {
+	c := &moduleValueCursor{values: o.values}
+	cf := &cuckooFilter{
+		bucketSize:    CF_DEFAULT_BUCKET_SIZE,
+		maxIterations: CF_DEFAULT_MAX_ITERATIONS,
+		expansion:     CF_DEFAULT_EXPANSION,
+	}
+	cf.numFilters = c.unsigned()
+	cf.numBuckets = c.unsigned()
+	cf.numItems = c.unsigned()
+	if o.encver >= CF_MIN_EXPANSION_ENC {
+		cf.numDeletes = c.unsigned()
+		cf.bucketSize = c.unsigned()
+		cf.maxIterations = c.unsigned()
+		cf.expansion = c.unsigned()
+	}
+	for i := uint64(0); i < cf.numFilters && c.err == nil; i++ {
+		if !c.end() && o.values[c.pos].IsUnsigned() {
+			_ = c.unsigned() // number of buckets of the sub filter, saved by newer versions
+		}
+		cf.filters = append(cf.filters, c.string())
+	}
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(o.values)-c.pos)
+	}
+	return cf, c.err
+}
+
+// getEncodedCuckooHeader returns the packed CFHeader of RedisBloom.
+func getEncodedCuckooHeader(cf *cuckooFilter, withExpansion bool) string {
+	buf := new(bytes.Buffer)
+	_ = binary.Write(buf, binary.LittleEndian, []uint64{cf.numItems, cf.numBuckets, cf.numDeletes, cf.numFilters})
+	if withExpansion {
+		_ = binary.Write(buf, binary.LittleEndian, []uint16{uint16(cf.bucketSize), uint16(cf.maxIterations), uint16(cf.expansion)})
+	}
+	return buf.String()
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCuckooObject(t *testing.T) {
+	// two sub filters, the second one with its number of buckets
+	it := parseModule(t, "MBbloomCF", CF_MIN_EXPANSION_ENC, "k", func(w *valueWriter) {
+		w.unsigned(2) // filters
+		w.unsigned(4) // buckets
+		w.unsigned(3) // items
+		w.unsigned(1) // deletes
+		w.unsigned(2) // bucket size
+		w.unsigned(20)
+		w.unsigned(1)
+		w.string("abcd")
+		w.unsigned(8)
+		w.string("efghijkl")
+	})
+	cf := &cuckooFilter{numFilters: 2, numBuckets: 4, numItems: 3, numDeletes: 1, bucketSize: 2, maxIterations: 20, expansion: 1}
+	want := []RedisCmd{
+		{"CF.LOADCHUNK", "k", "1", getEncodedCuckooHeader(cf, true)},
+		{"CF.LOADCHUNK", "k", "5", "abcd"},
+		{"CF.LOADCHUNK", "k", "13", "efghijkl"},
+	}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+	if header := want[0][3]; len(header) != 4*8+3*2 {
+		t.Errorf("got header of %d bytes", len(header))
+	}
+}
+
+func TestCuckooObjectWithoutExpansion(t *testing.T) {
+	it := parseModule(t, "MBbloomCF", CF_MIN_EXPANSION_ENC-1, "k", func(w *valueWriter) {
+		w.unsigned(1)
+		w.unsigned(2)
+		w.unsigned(1)
+		w.string("ab")
+	})
+	cf := &cuckooFilter{numFilters: 1, numBuckets: 2, numItems: 1}
+	want := []RedisCmd{
+		{"CF.LOADCHUNK", "k", "1", getEncodedCuckooHeader(cf, false)},
+		{"CF.LOADCHUNK", "k", "3", "ab"},
+	}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+
+	it = parseModule(t, "MBbloomCF", CF_MIN_EXPANSION_ENC-1, "k", func(w *valueWriter) {
+		w.unsigned(2) // filters
+		w.unsigned(2)
+		w.unsigned(1)
+		w.string("ab")
+	})
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+}
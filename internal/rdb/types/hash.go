@@ -1,19 +1,24 @@
 package types
 
 import (
+	"RedisShake/internal/config"
 	"RedisShake/internal/log"
 	"RedisShake/internal/rdb/structure"
 	"io"
+	"strconv"
+	"sync"
 )
 
 type HashObject struct {
-	key   string
-	value map[string]string
+	key      string
+	value    map[string]string
+	expireAt map[string]int64 // absolute expire time of fields in milliseconds, since 7.4
 }
 
 func (o *HashObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
 	o.key = key
 	o.value = make(map[string]string)
+	o.expireAt = make(map[string]int64)
 	switch typeByte {
 	case rdbTypeHash:
 		o.readHash(rd)
@@ -23,6 +28,10 @@ func (o *HashObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
 		o.readHashZiplist(rd)
 	case rdbTypeHashListpack:
 		o.readHashListpack(rd)
+	case rdbTypeHashMetadataPreGA, rdbTypeHashMetadata:
+		o.readHashMetadata(rd, typeByte)
+	case rdbTypeHashListpackExPreGA, rdbTypeHashListpackEx:
+		o.readHashListpackEx(rd, typeByte)
 	default:
 		log.Panicf("unknown hash type. typeByte=[%d]", typeByte)
 	}
@@ -38,7 +47,13 @@ func (o *HashObject) readHash(rd io.Reader) {
 }
 
 func (o *HashObject) readHashZipmap(rd io.Reader) {
-	log.Panicf("not implemented rdbTypeZipmap")
+	list := structure.ReadZipmap(rd)
+	size := len(list)
+	for i := 0; i < size; i += 2 {
+		key := list[i]
+		value := list[i+1]
+		o.value[key] = value
+	}
 }
 
 func (o *HashObject) readHashZiplist(rd io.Reader) {
@@ -61,11 +76,91 @@ func (o *HashObject) readHashListpack(rd io.Reader) {
 	}
 }
 
+// readHashMetadata reads the hash with field expiration. Each field is saved as
+// <ttl><field><value>. The ttl of 7.4 rc is the absolute expire time, since 7.4
+// GA it is relative to the minimum expire time saved before the fields.
+func (o *HashObject) readHashMetadata(rd io.Reader, typeByte byte) {
+	var minExpire int64
+	if typeByte == rdbTypeHashMetadata {
+		minExpire = int64(structure.ReadUint64(rd))
+	}
+	size := int(structure.ReadLength(rd))
+	for i := 0; i < size; i++ {
+		key, value, expireAt := readHashMetadataField(rd, typeByte, minExpire)
+		o.value[key] = value
+		if expireAt != 0 {
+			o.expireAt[key] = expireAt
+		}
+	}
+}
+
+// readHashMetadataField reads a field of readHashMetadata, the expire time is 0
+// if no expire.
+func readHashMetadataField(rd io.Reader, typeByte byte, minExpire int64) (key string, value string, expireAt int64) {
+	ttl := int64(structure.ReadLength(rd))
+	key = structure.ReadString(rd)
+	value = structure.ReadString(rd)
+	if ttl == 0 { // no expire
+		return
+	}
+	if typeByte == rdbTypeHashMetadata {
+		expireAt = ttl + minExpire - 1
+	} else {
+		expireAt = ttl
+	}
+	return
+}
+
+// readHashListpackEx reads the listpack of [field, value, expire time] with
+// field expiration, the expire time is 0 if no expire.
+func (o *HashObject) readHashListpackEx(rd io.Reader, typeByte byte) {
+	if typeByte == rdbTypeHashListpackEx {
+		_ = structure.ReadUint64(rd) // minimum expire time
+	}
+	list := structure.ReadListpack(rd)
+	size := len(list)
+	for i := 0; i < size; i += 3 {
+		key := list[i]
+		value := list[i+1]
+		o.value[key] = value
+		expireAt, err := strconv.ParseInt(list[i+2], 10, 64)
+		if err != nil {
+			log.Panicf("invalid expire time of hash field. key=[%s], field=[%s], expire=[%s]", o.key, key, list[i+2])
+		}
+		if expireAt != 0 {
+			o.expireAt[key] = expireAt
+		}
+	}
+}
+
 func (o *HashObject) Rewrite() []RedisCmd {
 	var cmds []RedisCmd
 	for k, v := range o.value {
-		cmd := RedisCmd{"hset", o.key, k, v}
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, hashFieldCmds(o.key, k, v, o.expireAt[k])...)
+	}
+	return batchCmds(cmds)
+}
+
+func hashFieldCmds(key string, field string, value string, expireAt int64) []RedisCmd {
+	cmds := []RedisCmd{{"hset", key, field, value}}
+	if expireAt != 0 && TargetSupportsHashFieldTTL(key) {
+		cmds = append(cmds, RedisCmd{"hpexpireat", key, strconv.FormatInt(expireAt, 10), "fields", "1", field})
 	}
 	return cmds
 }
+
+var dropHashFieldTTLOnce sync.Once
+
+// TargetSupportsHashFieldTTL returns false if the target is older than 7.4,
+// which has no HPEXPIREAT. The expire time of the hash fields is dropped
+// then, with a warning for the first key.
+func TargetSupportsHashFieldTTL(key string) bool {
+	if config.Opt.Target.Version >= 7.4 {
+		return true
+	}
+	dropHashFieldTTLOnce.Do(func() {
+		log.Warnf("the target is older than 7.4, the expire time of hash fields is dropped and the fields never expire, the other keys are dropped silently. key=[%s], target_version=[%v]",
+			key, config.Opt.Target.Version)
+	})
+	return false
+}
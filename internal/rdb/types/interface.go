@@ -16,10 +16,16 @@ const (
 	HashType = "hash"
 	// ZSetType is redis sorted set
 	ZSetType = "zset"
+	// StreamType is redis stream
+	StreamType = "stream"
+	// ModuleType is the value of a module
+	ModuleType = "module"
 	// AuxType is redis metadata key-value pair
 	AuxType = "aux"
 	// DBSizeType is for _OPCODE_RESIZEDB
 	DBSizeType = "dbsize"
+	// UnknownType is a type byte that redis-shake does not know
+	UnknownType = "unknown"
 )
 
 const (
@@ -46,6 +52,13 @@ const (
 	rdbTypeListQuicklist2   = 18 // RDB_TYPE_LIST_QUICKLIST_2 https://github.com/redis/redis/pull/9357
 	rdbTypeStreamListpacks2 = 19 // RDB_TYPE_STREAM_LISTPACKS2
 
+	rdbTypeSetListpack         = 20 // RDB_TYPE_SET_LISTPACK
+	rdbTypeStreamListpacks3    = 21 // RDB_TYPE_STREAM_LISTPACKS_3
+	rdbTypeHashMetadataPreGA   = 22 // RDB_TYPE_HASH_METADATA_PRE_GA, hash with field expiration of 7.4 rc
+	rdbTypeHashListpackExPreGA = 23 // RDB_TYPE_HASH_LISTPACK_EX_PRE_GA
+	rdbTypeHashMetadata        = 24 // RDB_TYPE_HASH_METADATA, hash with field expiration
+	rdbTypeHashListpackEx      = 25 // RDB_TYPE_HASH_LISTPACK_EX
+
 	moduleTypeNameCharSet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
 
 	rdbModuleOpcodeEOF    = 0 // End of module value.
@@ -64,6 +77,8 @@ type RedisObject interface {
 	Rewrite() []RedisCmd
 }
 
+// ParseObject parses the value of the type. It returns nil if the key should be
+// skipped, see unknown_module_behavior.
 func ParseObject(rd io.Reader, typeByte byte, key string) RedisObject {
 	switch typeByte {
 	case rdbTypeString: // string
@@ -74,7 +89,7 @@ func ParseObject(rd io.Reader, typeByte byte, key string) RedisObject {
 		o := new(ListObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
-	case rdbTypeSet, rdbTypeSetIntset: // set
+	case rdbTypeSet, rdbTypeSetIntset, rdbTypeSetListpack: // set
 		o := new(SetObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
@@ -82,11 +97,12 @@ func ParseObject(rd io.Reader, typeByte byte, key string) RedisObject {
 		o := new(ZsetObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
-	case rdbTypeHash, rdbTypeHashZipmap, rdbTypeHashZiplist, rdbTypeHashListpack: // hash
+	case rdbTypeHash, rdbTypeHashZipmap, rdbTypeHashZiplist, rdbTypeHashListpack,
+		rdbTypeHashMetadataPreGA, rdbTypeHashListpackExPreGA, rdbTypeHashMetadata, rdbTypeHashListpackEx: // hash
 		o := new(HashObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
-	case rdbTypeStreamListpacks, rdbTypeStreamListpacks2: // stream
+	case rdbTypeStreamListpacks, rdbTypeStreamListpacks2, rdbTypeStreamListpacks3: // stream
 		o := new(StreamObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
@@ -98,6 +114,29 @@ func ParseObject(rd io.Reader, typeByte byte, key string) RedisObject {
 	return nil
 }
 
+// TypeName returns the name of the type as the TYPE command does, such as
+// "hash" for all the encodings of hash, or UnknownType.
+func TypeName(typeByte byte) string {
+	switch typeByte {
+	case rdbTypeString:
+		return StringType
+	case rdbTypeList, rdbTypeListZiplist, rdbTypeListQuicklist, rdbTypeListQuicklist2:
+		return ListType
+	case rdbTypeSet, rdbTypeSetIntset, rdbTypeSetListpack:
+		return SetType
+	case rdbTypeZSet, rdbTypeZSet2, rdbTypeZSetZiplist, rdbTypeZSetListpack:
+		return ZSetType
+	case rdbTypeHash, rdbTypeHashZipmap, rdbTypeHashZiplist, rdbTypeHashListpack,
+		rdbTypeHashMetadataPreGA, rdbTypeHashListpackExPreGA, rdbTypeHashMetadata, rdbTypeHashListpackEx:
+		return HashType
+	case rdbTypeStreamListpacks, rdbTypeStreamListpacks2, rdbTypeStreamListpacks3:
+		return StreamType
+	case rdbTypeModule, rdbTypeModule2:
+		return ModuleType
+	}
+	return UnknownType
+}
+
 func moduleTypeNameByID(moduleId uint64) string {
 	nameList := make([]byte, 9)
 	moduleId >>= 10
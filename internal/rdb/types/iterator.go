@@ -0,0 +1,136 @@
+package types
+
+import (
+	"RedisShake/internal/config"
+	"RedisShake/internal/rdb/structure"
+	"io"
+)
+
+const rewriteBatchSize = 1024 // commands per batch of RewriteIterator
+
+// RewriteIterator yields the rewrite commands of a value in batches while the
+// value is being decoded, so a huge key is never held in memory as a whole.
+type RewriteIterator interface {
+	// Next returns the next batch of commands, or nil if there is no more.
+	Next() []RedisCmd
+	// Consumed returns true if the value has been read to the end, then Next
+	// only builds the commands, which is not needed if the value is restored.
+	Consumed() bool
+	// Err returns the reason if the value can not be rewritten, Next returns
+	// nil then.
+	Err() error
+}
+
+// NewRewriteIterator returns the RewriteIterator of the value of the type. The
+// types that are not saved element by element, such as ziplist, listpack,
+// stream and module, are parsed by ParseObject as a whole and rewritten in one
+// batch. It returns nil if the key should be skipped, like ParseObject.
+func NewRewriteIterator(rd io.Reader, typeByte byte, key string) RewriteIterator {
+	switch typeByte {
+	case rdbTypeList:
+		return newElementIterator(rd, func() []RedisCmd {
+			return []RedisCmd{{"rpush", key, structure.ReadString(rd)}}
+		})
+	case rdbTypeListQuicklist:
+		return newElementIterator(rd, func() []RedisCmd {
+			return listCmds(key, structure.ReadZipList(rd))
+		})
+	case rdbTypeListQuicklist2:
+		return newElementIterator(rd, func() []RedisCmd {
+			return listCmds(key, readQuickList2Node(rd))
+		})
+	case rdbTypeSet:
+		return newElementIterator(rd, func() []RedisCmd {
+			return []RedisCmd{{"sadd", key, structure.ReadString(rd)}}
+		})
+	case rdbTypeZSet, rdbTypeZSet2:
+		return newElementIterator(rd, func() []RedisCmd {
+			ele := readZsetEntry(rd, typeByte)
+			return []RedisCmd{{"zadd", key, ele.Score, ele.Member}}
+		})
+	case rdbTypeHash:
+		return newElementIterator(rd, func() []RedisCmd {
+			field := structure.ReadString(rd)
+			value := structure.ReadString(rd)
+			return hashFieldCmds(key, field, value, 0)
+		})
+	case rdbTypeHashMetadataPreGA, rdbTypeHashMetadata:
+		var minExpire int64
+		if typeByte == rdbTypeHashMetadata {
+			minExpire = int64(structure.ReadUint64(rd))
+		}
+		return newElementIterator(rd, func() []RedisCmd {
+			field, value, expireAt := readHashMetadataField(rd, typeByte, minExpire)
+			return hashFieldCmds(key, field, value, expireAt)
+		})
+	}
+	o := ParseObject(rd, typeByte, key)
+	if o == nil {
+		return nil
+	}
+	return &objectIterator{o: o}
+}
+
+// elementIterator reads the length of the value, then calls readElement that
+// many times, each returns the commands of an element or a quicklist node.
+type elementIterator struct {
+	remaining   uint64
+	readElement func() []RedisCmd
+}
+
+func newElementIterator(rd io.Reader, readElement func() []RedisCmd) *elementIterator {
+	return &elementIterator{
+		remaining:   structure.ReadLength(rd),
+		readElement: readElement,
+	}
+}
+
+func (it *elementIterator) Next() []RedisCmd {
+	limit := uint64(rewriteBatchSize)
+	if config.Opt.Advanced.RewriteBatchCount > limit {
+		limit = config.Opt.Advanced.RewriteBatchCount
+	}
+	var cmds []RedisCmd
+	for it.remaining > 0 && uint64(len(cmds)) < limit {
+		cmds = append(cmds, it.readElement()...)
+		it.remaining--
+	}
+	if cmds == nil {
+		return nil
+	}
+	return batchCmds(cmds)
+}
+
+func (it *elementIterator) Consumed() bool {
+	return it.remaining == 0
+}
+
+func (it *elementIterator) Err() error {
+	return nil
+}
+
+// objectIterator returns the Rewrite of a parsed object in one batch.
+type objectIterator struct {
+	o    RedisObject
+	done bool
+	err  error
+}
+
+func (it *objectIterator) Next() []RedisCmd {
+	if it.done {
+		return nil
+	}
+	it.done = true
+	cmds, err := RewriteObject(it.o)
+	it.err = err
+	return cmds
+}
+
+// Consumed is always true, the object is parsed by NewRewriteIterator.
+func (it *objectIterator) Consumed() bool {
+	return true
+}
+
+func (it *objectIterator) Err() error {
+	return it.err
+}
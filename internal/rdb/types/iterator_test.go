@@ -0,0 +1,138 @@
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"RedisShake/internal/config"
+)
+
+// drain returns the batches of the iterator until Next returns nil.
+func drain(it RewriteIterator) [][]RedisCmd {
+	var batches [][]RedisCmd
+	for cmds := it.Next(); cmds != nil; cmds = it.Next() {
+		batches = append(batches, cmds)
+	}
+	return batches
+}
+
+func TestElementIteratorBatches(t *testing.T) {
+	w := new(valueWriter)
+	n := rewriteBatchSize*2 + 1
+	w.length(uint64(n))
+	for i := 0; i < n; i++ {
+		w.rdbString(strconv.Itoa(i))
+	}
+	rd := bytes.NewReader(w.Bytes())
+	it := NewRewriteIterator(rd, rdbTypeList, "l")
+	if _, ok := it.(*elementIterator); !ok || it.Consumed() {
+		t.Fatalf("want an elementIterator that is not consumed, got %T", it)
+	}
+
+	// the elements are read batch by batch
+	first := it.Next()
+	if len(first) != rewriteBatchSize || it.Consumed() {
+		t.Fatalf("got %d commands, consumed=%v", len(first), it.Consumed())
+	}
+	if want := (RedisCmd{"rpush", "l", "0"}); !reflect.DeepEqual(first[0], want) {
+		t.Errorf("got %q, want %q", first[0], want)
+	}
+	if rd.Len() == 0 {
+		t.Errorf("the value is read as a whole")
+	}
+	batches := drain(it)
+	if len(batches) != 2 || len(batches[0]) != rewriteBatchSize || len(batches[1]) != 1 {
+		t.Fatalf("got %d batches", len(batches))
+	}
+	if want := (RedisCmd{"rpush", "l", strconv.Itoa(n - 1)}); !reflect.DeepEqual(batches[1][0], want) {
+		t.Errorf("got %q, want %q", batches[1][0], want)
+	}
+	if !it.Consumed() || it.Err() != nil || rd.Len() != 0 {
+		t.Errorf("consumed=%v, error=[%v], %d bytes left", it.Consumed(), it.Err(), rd.Len())
+	}
+}
+
+func TestElementIteratorQuicklist2(t *testing.T) {
+	w := new(valueWriter)
+	w.length(2)
+	w.length(quicklistNodeContainerPacked)
+	w.rdbString(listpack("a", "1"))
+	w.length(quicklistNodeContainerPlain)
+	w.rdbString("large")
+	want := [][]RedisCmd{{{"rpush", "l", "a"}, {"rpush", "l", "1"}, {"rpush", "l", "large"}}}
+	if got := drain(NewRewriteIterator(bytes.NewReader(w.Bytes()), rdbTypeListQuicklist2, "l")); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestElementIteratorHashMetadata(t *testing.T) {
+	defer func(opt config.ShakeOptions) { config.Opt = opt }(config.Opt)
+	config.Opt.Target.Version = 7.4
+	w := new(valueWriter)
+	w.millisecondTime(1000) // minimum expire time
+	w.length(2)
+	w.length(0) // no expire
+	w.rdbString("f1")
+	w.rdbString("v1")
+	w.length(501) // expire at 501 + 1000 - 1
+	w.rdbString("f2")
+	w.rdbString("v2")
+	want := [][]RedisCmd{{
+		{"hset", "h", "f1", "v1"},
+		{"hset", "h", "f2", "v2"},
+		{"hpexpireat", "h", "1500", "fields", "1", "f2"},
+	}}
+	if got := drain(NewRewriteIterator(bytes.NewReader(w.Bytes()), rdbTypeHashMetadata, "h")); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// the targets older than 7.4 have no HPEXPIREAT
+	config.Opt.Target.Version = 7.2
+	want[0] = want[0][:2]
+	if got := drain(NewRewriteIterator(bytes.NewReader(w.Bytes()), rdbTypeHashMetadata, "h")); !reflect.DeepEqual(got, want) {
+		t.Errorf("target 7.2: got %q, want %q", got, want)
+	}
+}
+
+func TestObjectIterator(t *testing.T) {
+	w := new(valueWriter)
+	w.rdbString("value")
+	rd := bytes.NewReader(w.Bytes())
+	it := NewRewriteIterator(rd, rdbTypeString, "k")
+	if _, ok := it.(*objectIterator); !ok || !it.Consumed() || rd.Len() != 0 {
+		t.Fatalf("want an objectIterator that is consumed, got %T", it)
+	}
+	want := [][]RedisCmd{{{"set", "k", "value"}}}
+	if got := drain(it); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+	if it.Next() != nil {
+		t.Errorf("the object is rewritten twice")
+	}
+}
+
+func TestObjectIteratorUnknownModule(t *testing.T) {
+	defer func(opt config.ModuleOptions) { config.Opt.Module = opt }(config.Opt.Module)
+	value := func() *bytes.Reader {
+		return bytes.NewReader(moduleValue("unknown-m", 1, func(w *valueWriter) { w.unsigned(1) }))
+	}
+
+	// passed through, it can only be restored
+	config.Opt.Module.UnknownModuleBehavior = "passthrough"
+	it := NewRewriteIterator(value(), rdbTypeModule2, "k")
+	if it == nil || !it.Consumed() {
+		t.Fatalf("want an objectIterator that is consumed, got %T", it)
+	}
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+
+	// skipped
+	config.Opt.Module.UnknownModuleBehavior = "skip"
+	rd := value()
+	if it := NewRewriteIterator(rd, rdbTypeModule2, "k"); it != nil || rd.Len() != 0 {
+		t.Errorf("got %T, %d bytes left, want nil", it, rd.Len())
+	}
+}
@@ -35,10 +35,13 @@ func (o *ListObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
 }
 
 func (o *ListObject) Rewrite() []RedisCmd {
-	cmds := make([]RedisCmd, len(o.elements))
-	for inx, ele := range o.elements {
-		cmd := RedisCmd{"rpush", o.key, ele}
-		cmds[inx] = cmd
+	return batchCmds(listCmds(o.key, o.elements))
+}
+
+func listCmds(key string, elements []string) []RedisCmd {
+	cmds := make([]RedisCmd, len(elements))
+	for inx, ele := range elements {
+		cmds[inx] = RedisCmd{"rpush", key, ele}
 	}
 	return cmds
 }
@@ -62,15 +65,17 @@ func (o *ListObject) readQuickList(rd io.Reader) {
 func (o *ListObject) readQuickList2(rd io.Reader) {
 	size := int(structure.ReadLength(rd))
 	for i := 0; i < size; i++ {
-		container := structure.ReadLength(rd)
-		if container == quicklistNodeContainerPlain {
-			ele := structure.ReadString(rd)
-			o.elements = append(o.elements, ele)
-		} else if container == quicklistNodeContainerPacked {
-			listpackElements := structure.ReadListpack(rd)
-			o.elements = append(o.elements, listpackElements...)
-		} else {
-			log.Panicf("unknown quicklist container %d", container)
-		}
+		o.elements = append(o.elements, readQuickList2Node(rd)...)
+	}
+}
+
+func readQuickList2Node(rd io.Reader) []string {
+	container := structure.ReadLength(rd)
+	if container == quicklistNodeContainerPlain {
+		return []string{structure.ReadString(rd)}
+	} else if container == quicklistNodeContainerPacked {
+		return structure.ReadListpack(rd)
 	}
+	log.Panicf("unknown quicklist container %d", container)
+	return nil
 }
@@ -1,16 +1,24 @@
 package types
 
 import (
+	"fmt"
 	"io"
 
+	"RedisShake/internal/config"
 	"RedisShake/internal/log"
 	"RedisShake/internal/rdb/structure"
+	"RedisShake/internal/status"
 )
 
 type ModuleObject interface {
 	RedisObject
 }
 
+// IsModuleType returns true if the type byte is a module value.
+func IsModuleType(typeByte byte) bool {
+	return typeByte == rdbTypeModule || typeByte == rdbTypeModule2
+}
+
 func PareseModuleType(rd io.Reader, key string, typeByte byte) ModuleObject {
 	if typeByte == rdbTypeModule {
 		log.Panicf("module type with version 1 is not supported, key=[%s]", key)
@@ -30,15 +38,90 @@ func PareseModuleType(rd io.Reader, key string, typeByte byte) ModuleObject {
 		o := new(TairZsetObject)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
+	case "ReJSON-RL":
+		o := new(JSONObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
+	case "TSDB-TYPE":
+		o := new(TSDBObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
 	case "MBbloom--":
 		o := new(BloomObject)
 		o.encver = int(moduleId & 1023)
 		o.LoadFromBuffer(rd, key, typeByte)
 		return o
+	case "MBbloomCF":
+		o := new(CuckooObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
+	case "TopK-TYPE":
+		o := new(TopKObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
+	case "CMSk-TYPE":
+		o := new(CMSObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
+	case "TDIS-TYPE":
+		o := new(TDigestObject)
+		o.encver = int(moduleId & 1023)
+		o.LoadFromBuffer(rd, key, typeByte)
+		return o
 	default:
-		log.Panicf("unsupported module type: %s", moduleName)
-		return nil
+		switch config.Opt.Module.UnknownModuleBehavior {
+		case "passthrough":
+			o := new(UnknownModuleObject)
+			o.moduleName = moduleName
+			o.LoadFromBuffer(rd, key, typeByte)
+			return o
+		case "skip":
+			_ = structure.ReadModuleValues(rd)
+			log.Warnf("skip key of unsupported module type. key=[%s], module=[%s]", key, moduleName)
+			status.AddSkippedKey("unknown_module")
+			return nil
+		default:
+			log.Panicf("unsupported module type: %s", moduleName)
+			return nil
+		}
+	}
+
+}
 
+// SkipModuleObject reads a module value without decoding it, and returns the
+// name of its module type, such as "MBbloom--". It returns false without
+// reading anything for module type with version 1, which can not be framed.
+func SkipModuleObject(rd io.Reader, typeByte byte) (string, bool) {
+	if typeByte == rdbTypeModule {
+		return "", false
 	}
+	moduleName := moduleTypeNameByID(structure.ReadLength(rd))
+	_ = structure.ReadModuleValues(rd)
+	return moduleName, true
+}
+
+// UnknownModuleObject is the value of a module that redis-shake does not know.
+// The value is read without understanding it, so it can only be passed
+// through by RESTORE.
+type UnknownModuleObject struct {
+	key        string
+	moduleName string
+}
+
+func (o *UnknownModuleObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	_ = structure.ReadModuleValues(rd)
+}
+
+func (o *UnknownModuleObject) Rewrite() []RedisCmd {
+	return nil
+}
 
+func (o *UnknownModuleObject) RewriteError() error {
+	return fmt.Errorf("the value of unsupported module type [%s] can only be restored, but it is too large or the target is too old to RESTORE it", o.moduleName)
 }
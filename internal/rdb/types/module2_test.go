@@ -0,0 +1,134 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// valueWriter writes values the way redis saves them to rdb, and module
+// values the way RedisModule_Save* of a module does, to build the fixtures of
+// the decoders.
+type valueWriter struct {
+	bytes.Buffer
+}
+
+// rdbString writes a string as rdbSaveRawString without compression.
+func (w *valueWriter) rdbString(s string) {
+	w.length(uint64(len(s)))
+	w.WriteString(s)
+}
+
+// millisecondTime writes a time as rdbSaveMillisecondTime.
+func (w *valueWriter) millisecondTime(ms uint64) {
+	_ = binary.Write(w, binary.LittleEndian, ms)
+}
+
+// listpack returns a listpack of the elements. The elements are small
+// integers (0 to 127) or strings shorter than 64 bytes, encoded as listpack
+// does.
+func listpack(elements ...string) string {
+	var entries bytes.Buffer
+	for _, element := range elements {
+		var entry []byte
+		if n, err := strconv.Atoi(element); err == nil && n >= 0 && n < 128 && strconv.Itoa(n) == element {
+			entry = []byte{byte(n)} // 7 bit uint
+		} else {
+			entry = append([]byte{0x80 | byte(len(element))}, element...) // 6 bit string
+		}
+		entries.Write(entry)
+		entries.WriteByte(byte(len(entry))) // backlen
+	}
+	buf := make([]byte, 6, 6+entries.Len()+1)
+	binary.LittleEndian.PutUint32(buf, uint32(6+entries.Len()+1))
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(elements)))
+	buf = append(buf, entries.Bytes()...)
+	return string(append(buf, 0xff))
+}
+
+func (w *valueWriter) length(n uint64) {
+	switch {
+	case n < 1<<6:
+		w.WriteByte(byte(n))
+	case n < 1<<14:
+		w.Write([]byte{byte(n>>8) | 0x40, byte(n)})
+	case n <= math.MaxUint32:
+		w.WriteByte(0x80)
+		_ = binary.Write(w, binary.BigEndian, uint32(n))
+	default:
+		w.WriteByte(0x81)
+		_ = binary.Write(w, binary.BigEndian, n)
+	}
+}
+
+func (w *valueWriter) unsigned(n uint64) {
+	w.length(2)
+	w.length(n)
+}
+
+func (w *valueWriter) signed(n int64) {
+	w.length(1)
+	w.length(uint64(n))
+}
+
+func (w *valueWriter) double(f float64) {
+	w.length(4)
+	_ = binary.Write(w, binary.LittleEndian, math.Float64bits(f))
+}
+
+func (w *valueWriter) float(f float32) {
+	w.length(3)
+	_ = binary.Write(w, binary.LittleEndian, math.Float32bits(f))
+}
+
+func (w *valueWriter) string(s string) {
+	w.length(5)
+	w.length(uint64(len(s)))
+	w.WriteString(s)
+}
+
+// cstring writes a string with its null terminator, as RedisModule_SaveStringBuffer(s, strlen(s)+1).
+func (w *valueWriter) cstring(s string) {
+	w.string(s + "\x00")
+}
+
+func (w *valueWriter) eof() {
+	w.length(0)
+}
+
+// moduleID returns the module id of the name of a module type and encver.
+func moduleID(name string, encver int) uint64 {
+	var id uint64
+	for i := 0; i < len(name); i++ {
+		id = id<<6 | uint64(strings.IndexByte(moduleTypeNameCharSet, name[i]))
+	}
+	return id<<10 | uint64(encver)
+}
+
+// moduleValue returns the payload of a rdbTypeModule2 value, the module id
+// and the values written by save.
+func moduleValue(name string, encver int, save func(w *valueWriter)) []byte {
+	w := new(valueWriter)
+	w.length(moduleID(name, encver))
+	save(w)
+	w.eof()
+	return w.Bytes()
+}
+
+// parseModule returns the iterator of the module value written by save, the
+// value must be read to the end.
+func parseModule(t *testing.T, name string, encver int, key string, save func(w *valueWriter)) *objectIterator {
+	t.Helper()
+	rd := bytes.NewReader(moduleValue(name, encver, save))
+	it, ok := NewRewriteIterator(rd, rdbTypeModule2, key).(*objectIterator)
+	if !ok {
+		t.Fatalf("want objectIterator")
+	}
+	if rd.Len() != 0 {
+		t.Fatalf("%d bytes left", rd.Len())
+	}
+	return it
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"io"
+
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb/structure"
+)
+
+// ParseModuleAux reads the module auxiliary data of RDB_OPCODE_MODULE_AUX,
+// which is global data of a module instead of a key. It returns the commands
+// to rebuild the data for the modules that are understood, and skips the
+// others.
+func ParseModuleAux(rd io.Reader) []RedisCmd {
+	moduleId := structure.ReadLength(rd)
+	moduleName := moduleTypeNameByID(moduleId)
+	encver := int(moduleId & 1023)
+	whenOpcode := structure.ReadLength(rd)
+	if whenOpcode != rdbModuleOpcodeUINT {
+		log.Panicf("invalid when opcode of module aux data. module=[%s], opcode=[%d]", moduleName, whenOpcode)
+	}
+	when := structure.ReadLength(rd)
+	// the values are read without knowing the module, so the rdb stream never
+	// goes out of sync even if the decoding below fails
+	values := structure.ReadModuleValues(rd)
+
+	switch moduleName {
+	case "ft_index0":
+		cmds, err := rewriteSearchIndexes(values, encver)
+		if err != nil {
+			log.Warnf("skip RediSearch index definitions that can not be decoded, please recreate them manually. encver=[%d], error=[%v]", encver, err)
+		}
+		return cmds
+	default:
+		log.Warnf("skip module aux data. module=[%s], encver=[%d], when=[%d], values=[%d]", moduleName, encver, when, len(values))
+		return nil
+	}
+}
+
+// moduleValueCursor reads the values of a module in the order they are saved.
+// The first mismatch is kept in err and later reads return zero values, so
+// the caller only needs to check err at the end.
+type moduleValueCursor struct {
+	values []structure.ModuleValue
+	pos    int
+	err    error
+}
+
+func (c *moduleValueCursor) next(kind string, ok func(structure.ModuleValue) bool) structure.ModuleValue {
+	if c.err != nil {
+		return structure.ModuleValue{}
+	}
+	if c.pos >= len(c.values) {
+		c.err = fmt.Errorf("want %s at value %d, but got the end", kind, c.pos)
+		return structure.ModuleValue{}
+	}
+	v := c.values[c.pos]
+	if !ok(v) {
+		c.err = fmt.Errorf("want %s at value %d, but got opcode %d", kind, c.pos, v.Opcode)
+		return structure.ModuleValue{}
+	}
+	c.pos++
+	return v
+}
+
+func (c *moduleValueCursor) unsigned() uint64 {
+	return c.next("unsigned", structure.ModuleValue.IsUnsigned).Uint
+}
+
+func (c *moduleValueCursor) signed() int64 {
+	return int64(c.next("signed", structure.ModuleValue.IsSigned).Uint)
+}
+
+// integer reads a signed or an unsigned, for the fields that the modules
+// save in either way between versions.
+func (c *moduleValueCursor) integer() int64 {
+	return int64(c.next("integer", func(v structure.ModuleValue) bool { return v.IsSigned() || v.IsUnsigned() }).Uint)
+}
+
+func (c *moduleValueCursor) double() float64 {
+	return c.next("double", structure.ModuleValue.IsDouble).Double
+}
+
+func (c *moduleValueCursor) string() string {
+	return c.next("string", structure.ModuleValue.IsString).String
+}
+
+// cstring reads a string saved with its null terminator.
+func (c *moduleValueCursor) cstring() string {
+	s := c.string()
+	if len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func (c *moduleValueCursor) end() bool {
+	return c.pos >= len(c.values)
+}
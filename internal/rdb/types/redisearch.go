@@ -0,0 +1,274 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb/structure"
+)
+
+// The index definitions of RediSearch are saved as module aux data of the
+// "ft_index0" type. Only the layouts with schema rules (indexes created by
+// FT.CREATE ... ON HASH|JSON) are decoded, older and newer layouts are skipped.
+const (
+	ftIndexMinEncver  = 17
+	ftIndexJSONEncver = 18 // fields have a path
+	ftIndexGeoEncver  = 22 // geometry fields have the coordinate system
+	ftIndexMaxEncver  = 22
+)
+
+// index flags
+const (
+	ftIndexStoreTermOffsets   = 0x01
+	ftIndexStoreFieldFlags    = 0x02
+	ftIndexHasCustomStopwords = 0x08
+	ftIndexStoreFreqs         = 0x10
+	ftIndexWideSchema         = 0x80
+	ftIndexHasSmap            = 0x100
+	ftIndexTemporary          = 0x200
+	ftIndexSkipInitialScan    = 0x1000
+)
+
+// field types
+const (
+	ftFieldFulltext = 0x01
+	ftFieldNumeric  = 0x02
+	ftFieldGeo      = 0x04
+	ftFieldTag      = 0x08
+	ftFieldVector   = 0x10
+	ftFieldGeometry = 0x20
+)
+
+// field options
+const (
+	ftFieldSortable       = 0x01
+	ftFieldNoStemming     = 0x02
+	ftFieldNotIndexable   = 0x04
+	ftFieldPhonetics      = 0x08
+	ftFieldDynamic        = 0x10
+	ftFieldUNF            = 0x20
+	ftFieldWithSuffixTrie = 0x40
+
+	ftFieldKnownOptions = ftFieldSortable | ftFieldNoStemming | ftFieldNotIndexable | ftFieldPhonetics |
+		ftFieldDynamic | ftFieldUNF | ftFieldWithSuffixTrie
+)
+
+// tag flags
+const (
+	ftTagCaseSensitive = 0x01
+)
+
+// ftLanguages are the names of RSLanguage, the default language saved as its
+// value.
+var ftLanguages = []string{"english", "arabic", "basque", "catalan", "chinese", "danish", "dutch", "finnish",
+	"french", "german", "greek", "hindi", "hungarian", "indonesian", "irish", "italian", "lithuanian", "nepali",
+	"norwegian", "portuguese", "romanian", "russian", "spanish", "swedish", "tamil", "turkish", "armenian",
+	"serbian", "yiddish"}
+
+// ftPhoneticMatchers are the double metaphone matchers of the languages. Only
+// the phonetic flag of a field is saved, the matcher is chosen by the default
+// language as FT.CREATE ... PHONETIC does not take another one.
+var ftPhoneticMatchers = map[string]string{
+	"english":    "dm:en",
+	"french":     "dm:fr",
+	"portuguese": "dm:pt",
+	"spanish":    "dm:es",
+}
+
+// rewriteSearchIndexes returns FT.CREATE and FT.ALIASADD for each index. An
+// index that can not be created as it was is skipped with a warning, and no
+// command is returned for it. The error is returned when the values can not
+// be decoded, then the indexes after it are skipped too.
+func rewriteSearchIndexes(values []structure.ModuleValue, encver int) ([]RedisCmd, error) {
+	if encver < ftIndexMinEncver || encver > ftIndexMaxEncver {
+		return nil, fmt.Errorf("unknown encver %d, only %d to %d are supported", encver, ftIndexMinEncver, ftIndexMaxEncver)
+	}
+	c := &moduleValueCursor{values: values}
+	count := c.unsigned()
+	var cmds []RedisCmd
+	for i := uint64(0); i < count && c.err == nil; i++ {
+		indexCmds, err := rewriteSearchIndex(c, encver)
+		if c.err != nil {
+			return cmds, fmt.Errorf("decode index %d of %d failed: %v", i+1, count, c.err)
+		}
+		if err != nil {
+			log.Warnf("skip RediSearch index, please recreate it manually. error=[%v]", err)
+			continue
+		}
+		cmds = append(cmds, indexCmds...)
+	}
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left after %d indexes", len(values)-c.pos, count)
+	}
+	return cmds, c.err
+}
+
+// rewriteSearchIndex reads an index. It returns an error if the index can not
+// be created as it was, after all the values of the index are read.
+func rewriteSearchIndex(c *moduleValueCursor, encver int) ([]RedisCmd, error) {
+	name := c.cstring()
+	flags := c.unsigned()
+	var unsupported error
+
+	var schema []string
+	var phonetics []int // positions of the matchers in schema, set by the default language
+	numFields := c.unsigned()
+	for i := uint64(0); i < numFields && c.err == nil; i++ {
+		fieldName := c.cstring()
+		if encver >= ftIndexJSONEncver && c.unsigned() == 1 { // has path
+			schema = append(schema, c.cstring(), "AS", fieldName)
+		} else {
+			schema = append(schema, fieldName)
+		}
+		types := c.unsigned()
+		options := c.unsigned()
+		_ = c.signed() // sort index
+		var weight float64
+		var tagFlags uint64
+		var tagSep string
+		if types&ftFieldFulltext != 0 || options&ftFieldDynamic != 0 {
+			_ = c.unsigned() // text field id
+			weight = c.double()
+		}
+		if types&ftFieldTag != 0 || options&ftFieldDynamic != 0 {
+			tagFlags = c.unsigned()
+			tagSep = c.string()
+		}
+		if types&ftFieldVector != 0 {
+			// the options of the vector algorithms are not decoded
+			c.err = fmt.Errorf("vector field %s is not supported", fieldName)
+			continue
+		}
+		if encver >= ftIndexGeoEncver && (types&ftFieldGeometry != 0 || options&ftFieldDynamic != 0) {
+			_ = c.unsigned() // coordinate system
+		}
+		if unsupported != nil {
+			continue
+		}
+		if options&^ftFieldKnownOptions != 0 {
+			unsupported = fmt.Errorf("index %s: unknown options %#x of field %s", name, options, fieldName)
+			continue
+		}
+		switch types {
+		case ftFieldFulltext:
+			schema = append(schema, "TEXT")
+			if weight != 1 {
+				schema = append(schema, "WEIGHT", strconv.FormatFloat(weight, 'f', -1, 64))
+			}
+			if options&ftFieldNoStemming != 0 {
+				schema = append(schema, "NOSTEM")
+			}
+			if options&ftFieldPhonetics != 0 {
+				schema = append(schema, "PHONETIC", "")
+				phonetics = append(phonetics, len(schema)-1)
+			}
+		case ftFieldNumeric:
+			schema = append(schema, "NUMERIC")
+		case ftFieldGeo:
+			schema = append(schema, "GEO")
+		case ftFieldTag:
+			schema = append(schema, "TAG")
+			if tagSep != "" && tagSep != "," {
+				schema = append(schema, "SEPARATOR", tagSep)
+			}
+			if tagFlags&ftTagCaseSensitive != 0 {
+				schema = append(schema, "CASESENSITIVE")
+			}
+		default:
+			unsupported = fmt.Errorf("index %s: unsupported type %#x of field %s", name, types, fieldName)
+			continue
+		}
+		if options&ftFieldWithSuffixTrie != 0 {
+			schema = append(schema, "WITHSUFFIXTRIE")
+		}
+		if options&ftFieldSortable != 0 {
+			schema = append(schema, "SORTABLE")
+			if options&ftFieldUNF != 0 {
+				schema = append(schema, "UNF")
+			}
+		}
+		if options&ftFieldNotIndexable != 0 {
+			schema = append(schema, "NOINDEX")
+		}
+	}
+
+	// schema rule
+	cmd := RedisCmd{"FT.CREATE", name, "ON", c.cstring()}
+	numPrefixes := c.unsigned()
+	var prefixes []string
+	for i := uint64(0); i < numPrefixes && c.err == nil; i++ {
+		prefixes = append(prefixes, c.cstring())
+	}
+	if len(prefixes) > 0 {
+		cmd = append(cmd, "PREFIX", strconv.Itoa(len(prefixes)))
+		cmd = append(cmd, prefixes...)
+	}
+	for _, option := range []string{"FILTER", "LANGUAGE_FIELD", "SCORE_FIELD", "PAYLOAD_FIELD"} {
+		if c.unsigned() == 1 {
+			cmd = append(cmd, option, c.cstring())
+		}
+	}
+	if score := c.double(); score != 1 {
+		cmd = append(cmd, "SCORE", strconv.FormatFloat(score, 'f', -1, 64))
+	}
+	language := c.unsigned()
+	if language < uint64(len(ftLanguages)) {
+		cmd = append(cmd, "LANGUAGE", ftLanguages[language])
+		matcher, ok := ftPhoneticMatchers[ftLanguages[language]]
+		if !ok && len(phonetics) > 0 && unsupported == nil {
+			unsupported = fmt.Errorf("index %s: no phonetic matcher of language %s", name, ftLanguages[language])
+		}
+		for _, pos := range phonetics {
+			schema[pos] = matcher
+		}
+	} else if unsupported == nil {
+		unsupported = fmt.Errorf("index %s: unknown language %d", name, language)
+	}
+
+	if flags&ftIndexTemporary != 0 && unsupported == nil {
+		unsupported = fmt.Errorf("index %s: temporary indexes are not supported", name)
+	}
+	if flags&ftIndexWideSchema != 0 {
+		cmd = append(cmd, "MAXTEXTFIELDS")
+	}
+	if flags&ftIndexStoreTermOffsets == 0 {
+		cmd = append(cmd, "NOOFFSETS")
+	}
+	if flags&ftIndexStoreFieldFlags == 0 {
+		cmd = append(cmd, "NOFIELDS")
+	}
+	if flags&ftIndexStoreFreqs == 0 {
+		cmd = append(cmd, "NOFREQS")
+	}
+	if flags&ftIndexSkipInitialScan != 0 {
+		cmd = append(cmd, "SKIPINITIALSCAN")
+	}
+	if flags&ftIndexHasCustomStopwords != 0 {
+		numStopwords := c.unsigned()
+		cmd = append(cmd, "STOPWORDS", strconv.FormatUint(numStopwords, 10))
+		for i := uint64(0); i < numStopwords && c.err == nil; i++ {
+			cmd = append(cmd, c.cstring())
+		}
+	}
+	if flags&ftIndexHasSmap != 0 {
+		// the synonym groups are not decoded
+		c.err = fmt.Errorf("synonyms are not supported")
+	}
+	_ = c.unsigned() // timeout
+	var aliases []RedisCmd
+	numAliases := c.unsigned()
+	for i := uint64(0); i < numAliases && c.err == nil; i++ {
+		aliases = append(aliases, RedisCmd{"FT.ALIASADD", c.cstring(), name})
+	}
+	if c.err != nil {
+		c.err = fmt.Errorf("index %s: %v", name, c.err)
+		return nil, c.err
+	}
+	if unsupported != nil {
+		return nil, unsupported
+	}
+	cmd = append(cmd, "SCHEMA")
+	cmd = append(cmd, schema...)
+	return append([]RedisCmd{cmd}, aliases...), nil
+}
@@ -0,0 +1,243 @@
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// searchIndex writes an index as IndexSpec_RdbSave of RediSearch 2.x, with
+// the fields written by fields.
+type searchIndex struct {
+	name      string
+	flags     uint64
+	fields    func(w *valueWriter, encver int)
+	numFields uint64
+	on        string
+	prefixes  []string
+	filter    string
+	score     float64
+	language  uint64 // index of ftLanguages
+	stopwords []string
+	aliases   []string
+}
+
+const ftIndexDefaultFlags = ftIndexStoreTermOffsets | ftIndexStoreFieldFlags | ftIndexStoreFreqs | 0x40 // Index_StoreByteOffsets
+
+func (idx *searchIndex) save(w *valueWriter, encver int) {
+	w.cstring(idx.name)
+	w.unsigned(idx.flags)
+	w.unsigned(idx.numFields)
+	idx.fields(w, encver)
+	// SchemaRule_RdbSave
+	w.cstring(idx.on)
+	w.unsigned(uint64(len(idx.prefixes)))
+	for _, prefix := range idx.prefixes {
+		w.cstring(prefix)
+	}
+	for _, option := range []string{idx.filter, "", "", ""} { // filter, lang, score and payload fields
+		if option == "" {
+			w.unsigned(0)
+		} else {
+			w.unsigned(1)
+			w.cstring(option)
+		}
+	}
+	w.double(idx.score)
+	w.unsigned(idx.language)
+	if idx.flags&ftIndexHasCustomStopwords != 0 {
+		w.unsigned(uint64(len(idx.stopwords)))
+		for _, word := range idx.stopwords {
+			w.cstring(word)
+		}
+	}
+	w.unsigned(500) // timeout
+	w.unsigned(uint64(len(idx.aliases)))
+	for _, alias := range idx.aliases {
+		w.cstring(alias)
+	}
+}
+
+// searchField writes a field as FieldSpec_RdbSave.
+func searchField(w *valueWriter, encver int, name, path string, types, options uint64, weight float64, tagFlags uint64, tagSep string) {
+	w.cstring(name)
+	if encver >= ftIndexJSONEncver {
+		if path == "" {
+			w.unsigned(0)
+		} else {
+			w.unsigned(1)
+			w.cstring(path)
+		}
+	}
+	w.unsigned(types)
+	w.unsigned(options)
+	w.signed(-1)
+	if types&ftFieldFulltext != 0 {
+		w.unsigned(0)
+		w.double(weight)
+	}
+	if types&ftFieldTag != 0 {
+		w.unsigned(tagFlags)
+		w.string(tagSep)
+	}
+	if encver >= ftIndexGeoEncver && types&ftFieldGeometry != 0 {
+		w.unsigned(1)
+	}
+}
+
+func searchAux(encver int, indexes ...*searchIndex) []byte {
+	w := new(valueWriter)
+	w.length(moduleID("ft_index0", encver))
+	w.length(rdbModuleOpcodeUINT)
+	w.length(1) // REDISMODULE_AUX_BEFORE_RDB
+	w.unsigned(uint64(len(indexes)))
+	for _, idx := range indexes {
+		idx.save(w, encver)
+	}
+	w.eof()
+	return w.Bytes()
+}
+
+var hashIndex = &searchIndex{
+	name:      "idx:product",
+	flags:     ftIndexDefaultFlags,
+	numFields: 4,
+	fields: func(w *valueWriter, encver int) {
+		searchField(w, encver, "title", "", ftFieldFulltext, ftFieldSortable|ftFieldNoStemming, 2, 0, "")
+		searchField(w, encver, "price", "", ftFieldNumeric, ftFieldSortable|ftFieldUNF, 0, 0, "")
+		searchField(w, encver, "tags", "", ftFieldTag, 0, 0, ftTagCaseSensitive, ";")
+		searchField(w, encver, "location", "", ftFieldGeo, 0, 0, 0, "")
+	},
+	on:       "HASH",
+	prefixes: []string{"product:", "item:"},
+	filter:   "@price>0",
+	score:    0.5,
+	aliases:  []string{"products"},
+}
+
+var hashIndexCmds = []RedisCmd{
+	{"FT.CREATE", "idx:product", "ON", "HASH", "PREFIX", "2", "product:", "item:", "FILTER", "@price>0", "SCORE", "0.5",
+		"LANGUAGE", "english", "SCHEMA", "title", "TEXT", "WEIGHT", "2", "NOSTEM", "SORTABLE", "price", "NUMERIC", "SORTABLE", "UNF",
+		"tags", "TAG", "SEPARATOR", ";", "CASESENSITIVE", "location", "GEO"},
+	{"FT.ALIASADD", "products", "idx:product"},
+}
+
+var jsonIndex = &searchIndex{
+	name:      "idx:user",
+	flags:     ftIndexStoreFieldFlags | ftIndexHasCustomStopwords,
+	numFields: 2,
+	fields: func(w *valueWriter, encver int) {
+		searchField(w, encver, "name", "$.name", ftFieldFulltext, ftFieldPhonetics, 1, 0, "")
+		searchField(w, encver, "city", "$.city", ftFieldTag, ftFieldNotIndexable|ftFieldSortable, 0, 0, ",")
+	},
+	on:        "JSON",
+	score:     1,
+	stopwords: []string{"a", "the"},
+}
+
+var jsonIndexCmds = []RedisCmd{
+	{"FT.CREATE", "idx:user", "ON", "JSON", "LANGUAGE", "english", "NOOFFSETS", "NOFREQS", "STOPWORDS", "2", "a", "the",
+		"SCHEMA", "$.name", "AS", "name", "TEXT", "PHONETIC", "dm:en", "$.city", "AS", "city", "TAG", "SORTABLE", "NOINDEX"},
+}
+
+func TestParseModuleAuxRediSearch(t *testing.T) {
+	got := ParseModuleAux(bytes.NewReader(searchAux(22, hashIndex, jsonIndex)))
+	want := append(append([]RedisCmd{}, hashIndexCmds...), jsonIndexCmds...)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestRewriteSearchIndexesLanguage(t *testing.T) {
+	french := *jsonIndex
+	french.name = "idx:french"
+	french.language = 8
+	german := *hashIndex // no phonetic field
+	german.name = "idx:german"
+	german.language = 9
+	germanPhonetic := *jsonIndex
+	germanPhonetic.name = "idx:german_phonetic"
+	germanPhonetic.language = 9
+	unknown := *hashIndex
+	unknown.name = "idx:unknown"
+	unknown.language = uint64(len(ftLanguages))
+
+	got := ParseModuleAux(bytes.NewReader(searchAux(22, &french, &german, &germanPhonetic, &unknown)))
+	want := []RedisCmd{
+		{"FT.CREATE", "idx:french", "ON", "JSON", "LANGUAGE", "french", "NOOFFSETS", "NOFREQS", "STOPWORDS", "2", "a", "the",
+			"SCHEMA", "$.name", "AS", "name", "TEXT", "PHONETIC", "dm:fr", "$.city", "AS", "city", "TAG", "SORTABLE", "NOINDEX"},
+		append(RedisCmd{}, hashIndexCmds[0]...),
+		{"FT.ALIASADD", "products", "idx:german"},
+	}
+	want[1][1] = "idx:german"
+	want[1][13] = "german"
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v\nwant %v", got, want)
+	}
+}
+
+func TestRewriteSearchIndexesEncver(t *testing.T) {
+	// fields have no path before encver 18
+	rd := bytes.NewReader(searchAux(17, hashIndex))
+	if got := ParseModuleAux(rd); !reflect.DeepEqual(got, hashIndexCmds) {
+		t.Fatalf("encver 17: got %v, want %v", got, hashIndexCmds)
+	}
+	if rd.Len() != 0 {
+		t.Fatalf("encver 17: %d bytes left", rd.Len())
+	}
+	// unknown layouts are skipped as a whole, never decoded partially
+	for _, encver := range []int{16, 23} {
+		rd := bytes.NewReader(searchAux(encver, hashIndex))
+		if got := ParseModuleAux(rd); len(got) != 0 {
+			t.Fatalf("encver %d: got %v, want nothing", encver, got)
+		}
+		if rd.Len() != 0 {
+			t.Fatalf("encver %d: %d bytes left", encver, rd.Len())
+		}
+	}
+}
+
+func TestRewriteSearchIndexesSkip(t *testing.T) {
+	temporary := *hashIndex
+	temporary.name = "idx:temporary"
+	temporary.flags |= ftIndexTemporary
+	geometry := *hashIndex
+	geometry.name = "idx:geometry"
+	geometry.numFields = 1
+	geometry.fields = func(w *valueWriter, encver int) {
+		searchField(w, encver, "shape", "", ftFieldGeometry, 0, 0, 0, "")
+	}
+	unknownOption := *hashIndex
+	unknownOption.name = "idx:missing"
+	unknownOption.numFields = 1
+	unknownOption.fields = func(w *valueWriter, encver int) {
+		searchField(w, encver, "price", "", ftFieldNumeric, 0x200, 0, 0, "") // INDEXMISSING
+	}
+
+	// the indexes that can not be created as they were are skipped one by one
+	got := ParseModuleAux(bytes.NewReader(searchAux(22, &temporary, &geometry, &unknownOption, jsonIndex)))
+	if !reflect.DeepEqual(got, jsonIndexCmds) {
+		t.Fatalf("got %v, want %v", got, jsonIndexCmds)
+	}
+
+	// the indexes after one that can not be decoded are skipped
+	vector := *hashIndex
+	vector.name = "idx:vector"
+	vector.numFields = 1
+	vector.fields = func(w *valueWriter, encver int) {
+		w.cstring("embedding")
+		w.unsigned(0)
+		w.unsigned(ftFieldVector)
+		w.unsigned(0)
+		w.signed(-1)
+		w.unsigned(1) // the vector options that are not decoded
+	}
+	rd := bytes.NewReader(searchAux(22, hashIndex, &vector, jsonIndex))
+	got = ParseModuleAux(rd)
+	if !reflect.DeepEqual(got, hashIndexCmds) {
+		t.Fatalf("got %v, want %v", got, hashIndexCmds)
+	}
+	if rd.Len() != 0 {
+		t.Fatalf("%d bytes left", rd.Len())
+	}
+}
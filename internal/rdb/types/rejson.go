@@ -0,0 +1,124 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"RedisShake/internal/rdb/structure"
+)
+
+// node types of the RedisJSON 1.x rdb format
+const (
+	rejsonNodeNull    = 1
+	rejsonNodeString  = 2
+	rejsonNodeNumber  = 4
+	rejsonNodeInteger = 8
+	rejsonNodeBoolean = 16
+	rejsonNodeDict    = 32
+	rejsonNodeArray   = 64
+	rejsonNodeKeyVal  = 128
+)
+
+// JSONObject is the value of RedisJSON, module type "ReJSON-RL". Since
+// RedisJSON 2.0 (encver 2 and 3) the document is saved as one JSON string,
+// RedisJSON 1.x (encver 0) saves it as a tree of nodes.
+type JSONObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+func (o *JSONObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+func (o *JSONObject) Rewrite() []RedisCmd {
+	doc, err := o.document()
+	if err != nil {
+		o.err = fmt.Errorf("can not decode the value of RedisJSON, encver=[%d]: %w", o.encver, err)
+		return nil
+	}
+	return []RedisCmd{{"JSON.SET", o.key, "$", doc}}
+}
+
+func (o *JSONObject) RewriteError() error {
+	return o.err
+}
+
+func (o *JSONObject) document() (string, error) {
+	c := &moduleValueCursor{values: o.values}
+	var doc string
+	switch o.encver {
+	case 0:
+		buf := new(bytes.Buffer)
+		readJSONNode(c, buf)
+		doc = buf.String()
+	case 2, 3:
+		doc = c.string()
+	default:
+		return "", fmt.Errorf("unsupported encver")
+	}
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(o.values)-c.pos)
+	}
+	return doc, c.err
+}
+
+// readJSONNode converts a node of RedisJSON 1.x to JSON.
+func readJSONNode(c *moduleValueCursor, buf *bytes.Buffer) {
+	nodeType := c.unsigned()
+	switch nodeType {
+	case rejsonNodeNull:
+		buf.WriteString("null")
+	case rejsonNodeBoolean:
+		if s := c.string(); len(s) > 0 && s[0] == '1' {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case rejsonNodeInteger:
+		buf.WriteString(strconv.FormatInt(c.signed(), 10))
+	case rejsonNodeNumber:
+		b, _ := json.Marshal(c.double())
+		buf.Write(b)
+	case rejsonNodeString:
+		b, _ := json.Marshal(c.string())
+		buf.Write(b)
+	case rejsonNodeDict:
+		size := c.unsigned()
+		buf.WriteByte('{')
+		for i := uint64(0); i < size && c.err == nil; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if t := c.unsigned(); t != rejsonNodeKeyVal && c.err == nil {
+				c.err = fmt.Errorf("want key-value node in dict, but got node type %d", t)
+				return
+			}
+			b, _ := json.Marshal(c.string())
+			buf.Write(b)
+			buf.WriteByte(':')
+			readJSONNode(c, buf)
+		}
+		buf.WriteByte('}')
+	case rejsonNodeArray:
+		size := c.unsigned()
+		buf.WriteByte('[')
+		for i := uint64(0); i < size && c.err == nil; i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			readJSONNode(c, buf)
+		}
+		buf.WriteByte(']')
+	default:
+		if c.err == nil {
+			c.err = fmt.Errorf("unknown node type %d", nodeType)
+		}
+	}
+}
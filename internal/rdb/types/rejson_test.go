@@ -0,0 +1,79 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONObjectEncver0(t *testing.T) {
+	// {"name":"shake","tags":[1,2.5,true,null],"empty":{}} saved by RedisJSON 1.x
+	it := parseModule(t, "ReJSON-RL", 0, "doc", func(w *valueWriter) {
+		w.unsigned(rejsonNodeDict)
+		w.unsigned(3)
+		w.unsigned(rejsonNodeKeyVal)
+		w.string("name")
+		w.unsigned(rejsonNodeString)
+		w.string("shake")
+		w.unsigned(rejsonNodeKeyVal)
+		w.string("tags")
+		w.unsigned(rejsonNodeArray)
+		w.unsigned(4)
+		w.unsigned(rejsonNodeInteger)
+		w.signed(1)
+		w.unsigned(rejsonNodeNumber)
+		w.double(2.5)
+		w.unsigned(rejsonNodeBoolean)
+		w.string("1")
+		w.unsigned(rejsonNodeNull)
+		w.unsigned(rejsonNodeKeyVal)
+		w.string("empty")
+		w.unsigned(rejsonNodeDict)
+		w.unsigned(0)
+	})
+	want := []RedisCmd{{"JSON.SET", "doc", "$", `{"name":"shake","tags":[1,2.5,true,null],"empty":{}}`}}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+}
+
+func TestJSONObjectEncver2And3(t *testing.T) {
+	for _, encver := range []int{2, 3} {
+		it := parseModule(t, "ReJSON-RL", encver, "doc", func(w *valueWriter) {
+			w.string(`{"a":[1,"b"]}`)
+		})
+		want := []RedisCmd{{"JSON.SET", "doc", "$", `{"a":[1,"b"]}`}}
+		if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+			t.Errorf("encver %d: got %q, error=[%v], want %q", encver, got, it.Err(), want)
+		}
+	}
+}
+
+func TestJSONObjectError(t *testing.T) {
+	tests := map[string]struct {
+		encver int
+		save   func(w *valueWriter)
+	}{
+		"unknown node type": {0, func(w *valueWriter) {
+			w.unsigned(3)
+		}},
+		"dict without key-value node": {0, func(w *valueWriter) {
+			w.unsigned(rejsonNodeDict)
+			w.unsigned(1)
+			w.unsigned(rejsonNodeString)
+			w.string("a")
+		}},
+		"values left": {2, func(w *valueWriter) {
+			w.string("{}")
+			w.string("{}")
+		}},
+		"unsupported encver": {4, func(w *valueWriter) {
+			w.string("{}")
+		}},
+	}
+	for name, test := range tests {
+		it := parseModule(t, "ReJSON-RL", test.encver, "doc", test.save)
+		if got := it.Next(); got != nil || it.Err() == nil {
+			t.Errorf("%s: got %q, error=[%v], want an error", name, got, it.Err())
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package types
+
+import (
+	"RedisShake/internal/config"
+	"RedisShake/internal/log"
+	"RedisShake/internal/status"
+	"strings"
+	"sync"
+)
+
+// deferredCmds are the rewrite commands that refer to other keys, so they are
+// sent after all the keys are migrated.
+var deferredCmds = map[string]bool{
+	"TS.CREATERULE": true, // TS.CREATERULE sourceKey destKey ..., the destination must exist
+}
+
+// IsDeferredCmd returns true if the rewrite command should be sent after all
+// the keys.
+func IsDeferredCmd(cmd RedisCmd) bool {
+	return len(cmd) > 0 && deferredCmds[strings.ToUpper(cmd[0])]
+}
+
+type deferredCmd struct {
+	dbId int
+	cmd  RedisCmd
+}
+
+type seriesKey struct {
+	dbId int
+	key  string
+}
+
+// DeferredCmds holds the deferred commands of a reader until all the keys
+// are sent. A TS.CREATERULE is only sent if both the source and the
+// destination series are rewritten by TS.CREATE: a restored series keeps its
+// own rules, and a series that is not sent makes the rule fail. The zero
+// value is ready to use, and it is safe for concurrent use.
+type DeferredCmds struct {
+	mu     sync.Mutex
+	cmds   []deferredCmd
+	series map[seriesKey]bool // rewritten by TS.CREATE
+}
+
+// Add is called with each command sent to the target, it returns true if the
+// command is deferred, which is not sent until Flush.
+func (d *DeferredCmds) Add(dbId int, cmd RedisCmd) bool {
+	isCreate := len(cmd) > 1 && strings.EqualFold(cmd[0], "TS.CREATE")
+	isDeferred := IsDeferredCmd(cmd)
+	if !isCreate && !isDeferred {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if isCreate {
+		if d.series == nil {
+			d.series = make(map[seriesKey]bool)
+		}
+		d.series[seriesKey{dbId, cmd[1]}] = true
+		return false
+	}
+	d.cmds = append(d.cmds, deferredCmd{dbId, cmd})
+	return true
+}
+
+// Flush sends the deferred commands added so far.
+func (d *DeferredCmds) Flush(send func(dbId int, cmd RedisCmd)) {
+	d.mu.Lock()
+	cmds := d.cmds
+	d.cmds = nil
+	d.mu.Unlock()
+	for _, c := range cmds {
+		if len(c.cmd) < 3 || !d.isSeries(c.dbId, c.cmd[1]) || !d.isSeries(c.dbId, c.cmd[2]) {
+			log.Warnf("skip the compaction rule, its source and destination series are not both rewritten. db=[%d], cmd=[%s]", c.dbId, strings.Join(c.cmd, " "))
+			continue
+		}
+		send(c.dbId, c.cmd)
+	}
+}
+
+func (d *DeferredCmds) isSeries(dbId int, key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.series[seriesKey{dbId, key}]
+}
+
+// RewriteObject returns the Rewrite of o, or the error if o can not be
+// rewritten.
+func RewriteObject(o RedisObject) ([]RedisCmd, error) {
+	cmds := o.Rewrite()
+	if f, ok := o.(fallibleObject); ok && f.RewriteError() != nil {
+		return nil, f.RewriteError()
+	}
+	return cmds, nil
+}
+
+// fallibleObject is implemented by the objects whose Rewrite may fail, such
+// as the module values that can not be decoded. Rewrite returns nil then.
+type fallibleObject interface {
+	RedisObject
+	RewriteError() error
+}
+
+// SkipUnrewritableKey handles a key whose value can not be rewritten, see
+// rewrite_failure_behavior.
+func SkipUnrewritableKey(key string, err error) {
+	switch config.Opt.Module.RewriteFailureBehavior {
+	case "skip":
+		log.Warnf("skip the key that can not be rewritten. key=[%s], error=[%v]", key, err)
+		status.AddSkippedKey("rewrite_failed")
+	case "panic":
+		log.Panicf("can not rewrite the key. key=[%s], error=[%v]", key, err)
+	default:
+		log.Panicf("invalid rewrite_failure_behavior: %s", config.Opt.Module.RewriteFailureBehavior)
+	}
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeferredCmds(t *testing.T) {
+	var d DeferredCmds
+	for _, c := range []struct {
+		dbId     int
+		cmd      RedisCmd
+		deferred bool
+	}{
+		{0, RedisCmd{"TS.CREATE", "src"}, false},
+		{0, RedisCmd{"TS.CREATERULE", "src", "dst", "AGGREGATION", "avg", "1000"}, true},
+		{0, RedisCmd{"TS.CREATERULE", "src", "restored", "AGGREGATION", "avg", "1000"}, true},
+		{0, RedisCmd{"TS.CREATERULE", "src", "other_db", "AGGREGATION", "avg", "1000"}, true},
+		{0, RedisCmd{"TS.MADD", "src", "1000", "1"}, false},
+		{0, RedisCmd{"RESTORE", "restored", "0", "payload"}, false},
+		{1, RedisCmd{"TS.CREATE", "other_db"}, false},
+		{0, RedisCmd{"ts.create", "dst"}, false}, // the destination comes later
+	} {
+		if got := d.Add(c.dbId, c.cmd); got != c.deferred {
+			t.Errorf("Add(%d, %q) = %v, want %v", c.dbId, c.cmd, got, c.deferred)
+		}
+	}
+	var got []RedisCmd
+	d.Flush(func(dbId int, cmd RedisCmd) { got = append(got, cmd) })
+	want := []RedisCmd{{"TS.CREATERULE", "src", "dst", "AGGREGATION", "avg", "1000"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got = nil
+	d.Flush(func(dbId int, cmd RedisCmd) { got = append(got, cmd) })
+	if got != nil {
+		t.Errorf("got %q after flush", got)
+	}
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseObjectSetListpack(t *testing.T) {
+	w := new(valueWriter)
+	w.rdbString(listpack("a", "100", "bc", "7"))
+	rd := bytes.NewReader(w.Bytes())
+	got := ParseObject(rd, rdbTypeSetListpack, "set").Rewrite()
+	want := []RedisCmd{{"sadd", "set", "a"}, {"sadd", "set", "100"}, {"sadd", "set", "bc"}, {"sadd", "set", "7"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if rd.Len() != 0 {
+		t.Fatalf("%d bytes left", rd.Len())
+	}
+
+	// the raw value is framed as a string
+	rd = bytes.NewReader(w.Bytes())
+	if !SkipObject(rd, rdbTypeSetListpack, func() bool { return false }) || rd.Len() != 0 {
+		t.Fatalf("skip set listpack failed, %d bytes left", rd.Len())
+	}
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"RedisShake/internal/rdb/structure"
+	"io"
+)
+
+// SkipObject reads the value of the type without decoding it, so the raw value
+// can be decoded later by another goroutine. tooLarge is checked after each
+// element, SkipObject stops and returns false once it is true. It also returns
+// false without reading anything for the types that are not framed, such as
+// module type with version 1, then the value must be decoded in place.
+func SkipObject(rd io.Reader, typeByte byte, tooLarge func() bool) bool {
+	skipElements := func(skipElement func()) bool {
+		size := structure.ReadLength(rd)
+		for i := uint64(0); i < size; i++ {
+			if tooLarge() {
+				return false
+			}
+			skipElement()
+		}
+		return !tooLarge()
+	}
+
+	switch typeByte {
+	case rdbTypeString, rdbTypeHashZipmap, rdbTypeListZiplist, rdbTypeSetIntset, rdbTypeZSetZiplist,
+		rdbTypeHashZiplist, rdbTypeHashListpack, rdbTypeZSetListpack, rdbTypeSetListpack:
+		structure.SkipString(rd)
+		return !tooLarge()
+	case rdbTypeList, rdbTypeSet, rdbTypeListQuicklist:
+		return skipElements(func() {
+			structure.SkipString(rd)
+		})
+	case rdbTypeListQuicklist2:
+		return skipElements(func() {
+			_ = structure.ReadLength(rd) // container
+			structure.SkipString(rd)
+		})
+	case rdbTypeZSet:
+		return skipElements(func() {
+			structure.SkipString(rd)
+			if n := structure.ReadUint8(rd); n < 253 { // 253, 254 and 255 are NaN and infinities
+				structure.SkipBytes(rd, int64(n))
+			}
+		})
+	case rdbTypeZSet2:
+		return skipElements(func() {
+			structure.SkipString(rd)
+			structure.SkipBytes(rd, 8)
+		})
+	case rdbTypeHash:
+		return skipElements(func() {
+			structure.SkipString(rd)
+			structure.SkipString(rd)
+		})
+	case rdbTypeHashMetadataPreGA, rdbTypeHashMetadata:
+		if typeByte == rdbTypeHashMetadata {
+			structure.SkipBytes(rd, 8) // minimum expire time
+		}
+		return skipElements(func() {
+			_ = structure.ReadLength(rd) // ttl
+			structure.SkipString(rd)
+			structure.SkipString(rd)
+		})
+	case rdbTypeHashListpackExPreGA, rdbTypeHashListpackEx:
+		if typeByte == rdbTypeHashListpackEx {
+			structure.SkipBytes(rd, 8) // minimum expire time
+		}
+		structure.SkipString(rd)
+		return !tooLarge()
+	case rdbTypeStreamListpacks, rdbTypeStreamListpacks2, rdbTypeStreamListpacks3:
+		if !skipElements(func() {
+			structure.SkipString(rd) // master id
+			structure.SkipString(rd) // listpack
+		}) {
+			return false
+		}
+		skipStreamMeta(rd, typeByte)
+		return !tooLarge()
+	case rdbTypeModule2:
+		_ = structure.ReadLength(rd) // module id
+		_ = structure.ReadModuleValues(rd)
+		return !tooLarge()
+	}
+	return false
+}
+
+// skipStreamMeta skips the fields of a stream after the listpacks, in the
+// order of StreamObject.readStream.
+func skipStreamMeta(rd io.Reader, typeByte byte) {
+	_ = structure.ReadLength(rd) // number of items
+	_ = structure.ReadLength(rd) // last id ms
+	_ = structure.ReadLength(rd) // last id seq
+	if typeByte >= rdbTypeStreamListpacks2 {
+		for i := 0; i < 5; i++ { // first id, max deleted id and offset
+			_ = structure.ReadLength(rd)
+		}
+	}
+	groups := structure.ReadLength(rd)
+	for i := uint64(0); i < groups; i++ {
+		structure.SkipString(rd)     // name
+		_ = structure.ReadLength(rd) // last id ms
+		_ = structure.ReadLength(rd) // last id seq
+		if typeByte >= rdbTypeStreamListpacks2 {
+			_ = structure.ReadLength(rd) // offset
+		}
+		pel := structure.ReadLength(rd)
+		for j := uint64(0); j < pel; j++ {
+			structure.SkipBytes(rd, 16+8) // id and delivery time
+			_ = structure.ReadLength(rd)  // delivery count
+		}
+		consumers := structure.ReadLength(rd)
+		for j := uint64(0); j < consumers; j++ {
+			structure.SkipString(rd)   // name
+			structure.SkipBytes(rd, 8) // seen time
+			if typeByte >= rdbTypeStreamListpacks3 {
+				structure.SkipBytes(rd, 8) // active time
+			}
+			structure.SkipBytes(rd, 16*int64(structure.ReadLength(rd))) // pel ids
+		}
+	}
+}
@@ -51,8 +51,10 @@ func (o *StreamObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
 		o.readStream(rd, key, typeByte)
 	case rdbTypeStreamListpacks2:
 		o.readStream(rd, key, typeByte)
+	case rdbTypeStreamListpacks3:
+		o.readStream(rd, key, typeByte)
 	default:
-		log.Panicf("unknown hash type. typeByte=[%d]", typeByte)
+		log.Panicf("unknown stream type. typeByte=[%d]", typeByte)
 	}
 }
 
@@ -138,7 +140,7 @@ func (o *StreamObject) readStream(rd io.Reader, masterKey string, typeByte byte)
 	 * in case of XDEL lastid. */
 	o.cmds = append(o.cmds, []string{"xsetid", masterKey, lastid})
 
-	if typeByte == rdbTypeStreamListpacks2 {
+	if typeByte >= rdbTypeStreamListpacks2 {
 		/* Load the first entry ID. */
 		_ = structure.ReadLength(rd) // first_ms
 		_ = structure.ReadLength(rd) // first_seq
@@ -165,10 +167,10 @@ func (o *StreamObject) readStream(rd io.Reader, masterKey string, typeByte byte)
 		lastid := fmt.Sprintf("%v-%v", lastMs, lastSeq)
 
 		/* Create Group */
-		o.cmds = append(o.cmds, []string{"CREATE", masterKey, groupName, lastid})
+		o.cmds = append(o.cmds, []string{"xgroup", "CREATE", masterKey, groupName, lastid})
 
 		/* Load group offset. */
-		if typeByte == rdbTypeStreamListpacks2 {
+		if typeByte >= rdbTypeStreamListpacks2 {
 			_ = structure.ReadLength(rd) // offset
 		}
 
@@ -205,6 +207,11 @@ func (o *StreamObject) readStream(rd io.Reader, masterKey string, typeByte byte)
 			/* Load lastSeenTime */
 			_ = structure.ReadUint64(rd)
 
+			/* Load activeTime */
+			if typeByte >= rdbTypeStreamListpacks3 {
+				_ = structure.ReadUint64(rd)
+			}
+
 			/* Consumer PEL */
 			nPEL := int(structure.ReadLength(rd))
 			for i := 0; i < nPEL; i++ {
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// streamID returns the 128 bit big endian id of the stream listpack keys and
+// the PELs.
+func streamID(ms, seq uint64) string {
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], ms)
+	binary.BigEndian.PutUint64(buf[8:], seq)
+	return string(buf[:])
+}
+
+// saveStream writes a stream of two entries, 1000-0 and 1001-0, with 1000-0
+// deleted, and a group with a consumer that has 1001-0 pending, as
+// rdbSaveObject of the type does.
+func saveStream(typeByte byte) []byte {
+	w := new(valueWriter)
+	w.length(1) // listpacks
+	w.rdbString(streamID(1000, 0))
+	w.rdbString(listpack(
+		"1", "1", "2", "f1", "f2", "0", // master entry: count, deleted, fields, 0
+		"3", "0", "0", "v1", "v2", "4", // deleted, same fields, 1000-0
+		"2", "1", "0", "w1", "w2", "4", // same fields, 1001-0
+	))
+	w.length(1)    // items
+	w.length(1001) // last id
+	w.length(0)
+	if typeByte >= rdbTypeStreamListpacks2 {
+		w.length(1000) // first id
+		w.length(0)
+		w.length(1000) // max deleted id
+		w.length(0)
+		w.length(2) // entries added
+	}
+	w.length(1) // groups
+	w.rdbString("group")
+	w.length(1001) // last delivered id
+	w.length(0)
+	if typeByte >= rdbTypeStreamListpacks2 {
+		w.length(2) // entries read
+	}
+	w.length(1) // global PEL
+	w.WriteString(streamID(1001, 0))
+	w.millisecondTime(1700000000000) // delivery time
+	w.length(3)                      // delivery count
+	w.length(1)                      // consumers
+	w.rdbString("consumer")
+	w.millisecondTime(1700000000001) // seen time
+	if typeByte >= rdbTypeStreamListpacks3 {
+		w.millisecondTime(1700000000002) // active time
+	}
+	w.length(1) // consumer PEL
+	w.WriteString(streamID(1001, 0))
+	return w.Bytes()
+}
+
+func TestParseObjectStream(t *testing.T) {
+	want := []RedisCmd{
+		{"xadd", "s", "1001-0", "f1", "w1", "f2", "w2"},
+		{"xsetid", "s", "1001-0"},
+		{"xgroup", "CREATE", "s", "group", "1001-0"},
+		{"xclaim", "s", "group", "consumer", "0", "1001-0", "TIME", "1700000000000", "RETRYCOUNT", "3", "JUSTID", "FORCE"},
+	}
+	for _, typeByte := range []byte{rdbTypeStreamListpacks, rdbTypeStreamListpacks2, rdbTypeStreamListpacks3} {
+		rd := bytes.NewReader(saveStream(typeByte))
+		got := ParseObject(rd, typeByte, "s").Rewrite()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("type %d: got %v, want %v", typeByte, got, want)
+		}
+		if rd.Len() != 0 {
+			t.Fatalf("type %d: %d bytes left", typeByte, rd.Len())
+		}
+		if TypeName(typeByte) != StreamType {
+			t.Fatalf("type %d: got type name %s", typeByte, TypeName(typeByte))
+		}
+
+		// the raw value is framed
+		rd = bytes.NewReader(saveStream(typeByte))
+		if !SkipObject(rd, typeByte, func() bool { return false }) || rd.Len() != 0 {
+			t.Fatalf("type %d: skip stream failed, %d bytes left", typeByte, rd.Len())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"RedisShake/internal/rdb/structure"
+)
+
+// TDigestObject for TDIS-TYPE at https://github.com/RedisBloom/RedisBloom
+//
+// TDIGEST.ADD has no weight argument and the values added are merged into
+// new centroids, so the centroids can not be loaded by commands. Only an empty
+// t-digest can be rewritten, by TDIGEST.CREATE with the same compression.
+type TDigestObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+type tdigest struct {
+	compression float64
+	means       []float64
+	weights     []float64
+}
+
+func (o *TDigestObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+func (o *TDigestObject) Rewrite() []RedisCmd {
+	td, err := o.decode()
+	if err != nil {
+		o.err = fmt.Errorf("can not decode the value of t-digest, encver=[%d]: %w", o.encver, err)
+		return nil
+	}
+	if len(td.means) != 0 {
+		o.err = fmt.Errorf("the centroids of t-digest can not be written by commands, centroids=[%d]", len(td.means))
+		return nil
+	}
+	return []RedisCmd{{"TDIGEST.CREATE", o.key, "COMPRESSION", strconv.FormatFloat(td.compression, 'f', -1, 64)}}
+}
+
+func (o *TDigestObject) RewriteError() error {
+	return o.err
+}
+
+func (o *TDigestObject) decode() (*tdigest, error) {
+	c := &moduleValueCursor{values: o.values}
+	td := new(tdigest)
+	td.compression = c.double()
+	_ = c.integer() // capacity
+	mergedNodes := c.integer()
+	unmergedNodes := c.integer()
+	_ = c.integer() // total compressions
+	// the weights are doubles in early versions and integers later
+	intWeights := !c.end() && !o.values[c.pos].IsDouble()
+	for i := 0; i < 2; i++ { // merged and unmerged weight
+		if intWeights {
+			_ = c.integer()
+		} else {
+			_ = c.double()
+		}
+	}
+	_ = c.double() // min
+	_ = c.double() // max
+	means := c.string()
+	weights := c.string()
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(o.values)-c.pos)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	n := int(mergedNodes + unmergedNodes)
+	if n < 0 || len(means) < n*8 || len(weights) < n*8 {
+		return nil, fmt.Errorf("invalid number of nodes %d", n)
+	}
+	for i := 0; i < n; i++ {
+		td.means = append(td.means, math.Float64frombits(binary.LittleEndian.Uint64([]byte(means[i*8:]))))
+		w := binary.LittleEndian.Uint64([]byte(weights[i*8:]))
+		if intWeights {
+			td.weights = append(td.weights, float64(int64(w)))
+		} else {
+			td.weights = append(td.weights, math.Float64frombits(w))
+		}
+	}
+	return td, nil
+}
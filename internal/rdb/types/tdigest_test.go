@@ -0,0 +1,91 @@
+package types
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// saveTDigest writes a t-digest as TDigestRdbSave, the weights are integers
+// as RedisBloom 2.4 and later or doubles as the earlier versions.
+func saveTDigest(w *valueWriter, means []float64, weights []float64, intWeights bool) {
+	w.double(100) // compression
+	w.signed(610) // capacity
+	w.signed(int64(len(means)))
+	w.signed(0) // unmerged nodes
+	w.signed(1) // total compressions
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	if intWeights {
+		w.signed(int64(total))
+		w.signed(0)
+	} else {
+		w.double(total)
+		w.double(0)
+	}
+	w.double(1) // min
+	w.double(9) // max
+	meansBuf := make([]byte, 8*len(means))
+	weightsBuf := make([]byte, 8*len(weights))
+	for i := range means {
+		binary.LittleEndian.PutUint64(meansBuf[i*8:], math.Float64bits(means[i]))
+		if intWeights {
+			binary.LittleEndian.PutUint64(weightsBuf[i*8:], uint64(weights[i]))
+		} else {
+			binary.LittleEndian.PutUint64(weightsBuf[i*8:], math.Float64bits(weights[i]))
+		}
+	}
+	w.string(string(meansBuf))
+	w.string(string(weightsBuf))
+}
+
+func TestTDigestObjectEmpty(t *testing.T) {
+	it := parseModule(t, "TDIS-TYPE", 0, "k", func(w *valueWriter) {
+		saveTDigest(w, nil, nil, true)
+	})
+	want := []RedisCmd{{"TDIGEST.CREATE", "k", "COMPRESSION", "100"}}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+}
+
+func TestTDigestObjectDecode(t *testing.T) {
+	for _, intWeights := range []bool{true, false} {
+		o := &TDigestObject{key: "k"}
+		w := new(valueWriter)
+		saveTDigest(w, []float64{1, 4.5, 9}, []float64{1, 3, 1}, intWeights)
+		w.eof()
+		o.LoadFromBuffer(w, "k", rdbTypeModule2)
+		td, err := o.decode()
+		if err != nil || td.compression != 100 ||
+			!reflect.DeepEqual(td.means, []float64{1, 4.5, 9}) || !reflect.DeepEqual(td.weights, []float64{1, 3, 1}) {
+			t.Fatalf("int weights %v: got %+v, error=[%v]", intWeights, td, err)
+		}
+		// the centroids can not be written
+		if got := o.Rewrite(); got != nil || o.RewriteError() == nil {
+			t.Errorf("int weights %v: got %q, error=[%v], want an error", intWeights, got, o.RewriteError())
+		}
+	}
+}
+
+func TestTDigestObjectInvalidNodes(t *testing.T) {
+	it := parseModule(t, "TDIS-TYPE", 0, "k", func(w *valueWriter) {
+		w.double(100)
+		w.signed(610)
+		w.signed(2) // merged nodes
+		w.signed(0)
+		w.signed(1)
+		w.signed(2)
+		w.signed(0)
+		w.double(1)
+		w.double(9)
+		w.string(string(make([]byte, 8))) // one mean
+		w.string(string(make([]byte, 16)))
+	})
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+}
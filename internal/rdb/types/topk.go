@@ -0,0 +1,89 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"RedisShake/internal/rdb/structure"
+)
+
+const topkHeapBucketSize = 24 // sizeof(HeapBucket): fp, itemlen, item pointer and count
+
+// TopKObject for TopK-TYPE at https://github.com/RedisBloom/RedisBloom
+//
+// The counters of the sketch and the heap can not be loaded by commands, so
+// only an empty Top-K can be rewritten, by TOPK.RESERVE with the same
+// parameters.
+type TopKObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+type topk struct {
+	k      uint64
+	width  uint64
+	depth  uint64
+	decay  float64
+	items  []string
+	counts []uint32
+	empty  bool // all the counters of the sketch are zero
+}
+
+func (o *TopKObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+func (o *TopKObject) Rewrite() []RedisCmd {
+	t, err := o.decode()
+	if err != nil {
+		o.err = fmt.Errorf("can not decode the value of Top-K, encver=[%d]: %w", o.encver, err)
+		return nil
+	}
+	if !t.empty || len(t.items) != 0 {
+		o.err = fmt.Errorf("the counters of Top-K can not be written by commands, items=[%d]", len(t.items))
+		return nil
+	}
+	return []RedisCmd{{"TOPK.RESERVE", o.key,
+		strconv.FormatUint(t.k, 10),
+		strconv.FormatUint(t.width, 10),
+		strconv.FormatUint(t.depth, 10),
+		strconv.FormatFloat(t.decay, 'f', -1, 64)}}
+}
+
+func (o *TopKObject) RewriteError() error {
+	return o.err
+}
+
+func (o *TopKObject) decode() (*topk, error) {
+	c := &moduleValueCursor{values: o.values}
+	t := new(topk)
+	t.k = c.unsigned()
+	t.width = c.unsigned()
+	t.depth = c.unsigned()
+	t.decay = c.double()
+	counters := c.string()
+	t.empty = strings.Trim(counters, "\x00") == ""
+	heap := c.string()
+	if c.err == nil && uint64(len(heap)) != t.k*topkHeapBucketSize {
+		return nil, fmt.Errorf("invalid heap size %d for k %d", len(heap), t.k)
+	}
+	for i := uint64(0); i < t.k && c.err == nil; i++ {
+		item := c.string()
+		count := binary.LittleEndian.Uint32([]byte(heap[i*topkHeapBucketSize+16:]))
+		if item == "\x00" || count == 0 { // empty slot of the heap
+			continue
+		}
+		t.items = append(t.items, item)
+		t.counts = append(t.counts, count)
+	}
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(o.values)-c.pos)
+	}
+	return t, c.err
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// saveTopK writes a Top-K of k = len(items) as TopK_rdb_save, the empty slots
+// of the heap have an empty item and a zero count.
+func saveTopK(w *valueWriter, counters string, items []string, counts []uint32) {
+	w.unsigned(uint64(len(items))) // k
+	w.unsigned(8)                  // width
+	w.unsigned(2)                  // depth
+	w.double(0.9)                  // decay
+	w.string(counters)
+	heap := make([]byte, len(items)*topkHeapBucketSize)
+	for i, count := range counts {
+		binary.LittleEndian.PutUint32(heap[i*topkHeapBucketSize+16:], count)
+	}
+	w.string(string(heap))
+	for _, item := range items {
+		w.string(item)
+	}
+}
+
+func TestTopKObjectEmpty(t *testing.T) {
+	it := parseModule(t, "TopK-TYPE", 0, "k", func(w *valueWriter) {
+		saveTopK(w, string(make([]byte, 8*2*8)), []string{"\x00", "\x00"}, []uint32{0, 0})
+	})
+	want := []RedisCmd{{"TOPK.RESERVE", "k", "2", "8", "2", "0.9"}}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+}
+
+func TestTopKObjectDecode(t *testing.T) {
+	counters := make([]byte, 8*2*8)
+	counters[3] = 1
+	o := &TopKObject{key: "k"}
+	w := new(valueWriter)
+	saveTopK(w, string(counters), []string{"a", "\x00", "b"}, []uint32{5, 0, 2})
+	w.eof()
+	o.LoadFromBuffer(w, "k", rdbTypeModule2)
+	tk, err := o.decode()
+	if err != nil || tk.k != 3 || tk.empty ||
+		!reflect.DeepEqual(tk.items, []string{"a", "b"}) || !reflect.DeepEqual(tk.counts, []uint32{5, 2}) {
+		t.Fatalf("got %+v, error=[%v]", tk, err)
+	}
+	// the counters can not be written
+	if got := o.Rewrite(); got != nil || o.RewriteError() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, o.RewriteError())
+	}
+}
+
+func TestTopKObjectInvalidHeap(t *testing.T) {
+	it := parseModule(t, "TopK-TYPE", 0, "k", func(w *valueWriter) {
+		w.unsigned(2)
+		w.unsigned(8)
+		w.unsigned(2)
+		w.double(0.9)
+		w.string("")
+		w.string(string(make([]byte, topkHeapBucketSize))) // one bucket for k 2
+		w.string("a")
+		w.string("b")
+	})
+	if got := it.Next(); got != nil || it.Err() == nil {
+		t.Errorf("got %q, error=[%v], want an error", got, it.Err())
+	}
+}
@@ -0,0 +1,334 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"RedisShake/internal/rdb/structure"
+)
+
+// The layout follows series_rdb_load of RedisTimeSeries, the fields are
+// added by encver.
+const (
+	tsdbMinEncver       = 3 // duplicate policy and the reset flag of the aggregation contexts
+	tsdbOverflowEncver  = 4 // the overflow flag of the avg context
+	tsdbAlignmentEncver = 5 // rules have timestamp alignment since this version
+	tsdbMaxEncver       = tsdbAlignmentEncver
+
+	tsdbOptUncompressed = 0x1
+	tsdbMaddBatchSize   = 1000 // samples per TS.MADD
+	tsdbSampleSize      = 16   // bytes of an uncompressed sample, timestamp and value
+)
+
+var tsdbAggregations = []string{"", "min", "max", "sum", "avg", "count", "first", "last", "range", "std.p", "std.s", "var.p", "var.s", "twa"}
+var tsdbDuplicatePolicies = []string{"", "block", "last", "first", "min", "max", "sum"}
+
+// tsdbContext returns the values of the aggregation context saved after a
+// rule, "d" for a double and "u" for an unsigned.
+func tsdbContext(aggregation uint64, encver int) (string, bool) {
+	switch tsdbAggregations[aggregation] {
+	case "min", "max", "range":
+		return "ddu", true // min, max and reset flag
+	case "sum", "count", "first", "last":
+		return "du", true // value and reset flag
+	case "avg":
+		if encver >= tsdbOverflowEncver {
+			return "ddu", true // value, count and overflow flag
+		}
+		return "dd", true
+	case "std.p", "std.s", "var.p", "var.s":
+		return "ddu", true // sum, sum of squares and count
+	}
+	return "", false
+}
+
+// TSDBObject is the value of RedisTimeSeries, module type "TSDB-TYPE".
+type TSDBObject struct {
+	encver int
+	key    string
+	values []structure.ModuleValue
+	err    error // set by Rewrite
+}
+
+type tsdbRule struct {
+	destKey        string
+	bucketDuration uint64
+	alignment      uint64
+	aggregation    uint64
+}
+
+type tsdbSeries struct {
+	retention       uint64
+	chunkSize       uint64
+	uncompressed    bool
+	duplicatePolicy uint64
+	labels          []string
+	rules           []tsdbRule
+	samples         []string // [timestamp1, value1, timestamp2, value2, ...]
+}
+
+func (o *TSDBObject) LoadFromBuffer(rd io.Reader, key string, typeByte byte) {
+	o.key = key
+	o.values = structure.ReadModuleValues(rd)
+}
+
+// Rewrite returns TS.CREATE with the options of the series, TS.MADD of the
+// samples and TS.CREATERULE of the compaction rules. TS.CREATERULE is sent
+// after all the keys, see DeferredCmds, because the destination may come
+// later in the rdb.
+func (o *TSDBObject) Rewrite() []RedisCmd {
+	s, err := o.decode()
+	if err != nil {
+		o.err = fmt.Errorf("can not decode the value of RedisTimeSeries, encver=[%d]: %w", o.encver, err)
+		return nil
+	}
+
+	encoding := "COMPRESSED"
+	if s.uncompressed {
+		encoding = "UNCOMPRESSED"
+	}
+	create := RedisCmd{"TS.CREATE", o.key,
+		"RETENTION", strconv.FormatUint(s.retention, 10),
+		"CHUNK_SIZE", strconv.FormatUint(s.chunkSize, 10),
+		"ENCODING", encoding}
+	if s.duplicatePolicy != 0 {
+		create = append(create, "DUPLICATE_POLICY", tsdbDuplicatePolicies[s.duplicatePolicy])
+	}
+	if len(s.labels) > 0 {
+		create = append(create, "LABELS")
+		create = append(create, s.labels...)
+	}
+	cmds := []RedisCmd{create}
+	for i := 0; i < len(s.samples); i += 2 * tsdbMaddBatchSize {
+		cmd := RedisCmd{"TS.MADD"}
+		for j := i; j < i+2*tsdbMaddBatchSize && j < len(s.samples); j += 2 {
+			cmd = append(cmd, o.key, s.samples[j], s.samples[j+1])
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, rule := range s.rules {
+		cmd := RedisCmd{"TS.CREATERULE", o.key, rule.destKey,
+			"AGGREGATION", tsdbAggregations[rule.aggregation], strconv.FormatUint(rule.bucketDuration, 10)}
+		if rule.alignment != 0 {
+			cmd = append(cmd, strconv.FormatUint(rule.alignment, 10))
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+func (o *TSDBObject) RewriteError() error {
+	return o.err
+}
+
+func (o *TSDBObject) decode() (*tsdbSeries, error) {
+	if o.encver < tsdbMinEncver {
+		return nil, fmt.Errorf("encver is older than %d", tsdbMinEncver)
+	}
+	if o.encver > tsdbMaxEncver {
+		return nil, fmt.Errorf("encver is newer than %d", tsdbMaxEncver)
+	}
+	s := new(tsdbSeries)
+	c := &moduleValueCursor{values: o.values}
+	_ = c.string() // key name
+	s.retention = c.unsigned()
+	s.chunkSize = c.unsigned()
+	options := c.unsigned()
+	_ = c.unsigned() // last timestamp
+	_ = c.double()   // last value
+	_ = c.unsigned() // total samples
+	s.duplicatePolicy = c.unsigned()
+	if c.unsigned() == 1 {
+		_ = c.string() // source key of the compaction rule, the rule is created with the source
+	}
+	labelsCount := c.unsigned()
+	for i := uint64(0); i < labelsCount && c.err == nil; i++ {
+		s.labels = append(s.labels, c.string(), c.string())
+	}
+	if s.duplicatePolicy >= uint64(len(tsdbDuplicatePolicies)) {
+		return nil, fmt.Errorf("unknown duplicate policy %d", s.duplicatePolicy)
+	}
+	s.uncompressed = options&tsdbOptUncompressed != 0
+
+	// rules: <dest key><bucket duration>[<alignment>]<aggregation><start of current bucket><context>
+	rulesCount := c.unsigned()
+	for i := uint64(0); i < rulesCount && c.err == nil; i++ {
+		rule := tsdbRule{destKey: c.string(), bucketDuration: c.unsigned()}
+		if o.encver >= tsdbAlignmentEncver {
+			rule.alignment = c.unsigned()
+		}
+		rule.aggregation = c.unsigned()
+		_ = c.unsigned() // start of current bucket
+		if c.err != nil {
+			break
+		}
+		if rule.aggregation == 0 || rule.aggregation >= uint64(len(tsdbAggregations)) {
+			return nil, fmt.Errorf("unknown aggregation type %d", rule.aggregation)
+		}
+		context, ok := tsdbContext(rule.aggregation, o.encver)
+		if !ok {
+			return nil, fmt.Errorf("the context of aggregation %s can not be parsed", tsdbAggregations[rule.aggregation])
+		}
+		for _, kind := range context {
+			if kind == 'd' {
+				_ = c.double()
+			} else {
+				_ = c.unsigned()
+			}
+		}
+		s.rules = append(s.rules, rule)
+	}
+
+	chunksCount := c.unsigned()
+	for i := uint64(0); i < chunksCount && c.err == nil; i++ {
+		var err error
+		if s.uncompressed {
+			err = s.readUncompressedChunk(c)
+		} else {
+			err = s.readCompressedChunk(c)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", i, err)
+		}
+	}
+	if c.err == nil && !c.end() {
+		c.err = fmt.Errorf("%d values left", len(c.values)-c.pos)
+	}
+	return s, c.err
+}
+
+func (s *tsdbSeries) appendSample(timestamp uint64, value float64) {
+	s.samples = append(s.samples, strconv.FormatUint(timestamp, 10), strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// readUncompressedChunk reads <base timestamp><number of samples><size><samples>,
+// each sample is a 8 bytes timestamp and a 8 bytes double.
+func (s *tsdbSeries) readUncompressedChunk(c *moduleValueCursor) error {
+	_ = c.unsigned() // base timestamp
+	samplesCount := c.unsigned()
+	_ = c.unsigned() // size
+	data := c.string()
+	if c.err != nil {
+		return nil // reported by the caller
+	}
+	if uint64(len(data)) < samplesCount*tsdbSampleSize {
+		return fmt.Errorf("chunk of %d bytes is too small for %d samples", len(data), samplesCount)
+	}
+	for j := uint64(0); j < samplesCount; j++ {
+		timestamp := binary.LittleEndian.Uint64([]byte(data[j*tsdbSampleSize:]))
+		value := math.Float64frombits(binary.LittleEndian.Uint64([]byte(data[j*tsdbSampleSize+8:])))
+		s.appendSample(timestamp, value)
+	}
+	return nil
+}
+
+// readCompressedChunk reads <size><count><bit index><base value><base timestamp>
+// <prev timestamp><prev timestamp delta><prev value><prev leading><prev trailing><data>
+// of Compressed_SaveToRDB. The fields after the base timestamp are the state
+// to append the next sample, the samples are decoded from the data.
+func (s *tsdbSeries) readCompressedChunk(c *moduleValueCursor) error {
+	_ = c.unsigned() // size
+	count := c.unsigned()
+	bits := c.unsigned()
+	baseValue := c.unsigned()
+	baseTimestamp := c.unsigned()
+	_ = c.unsigned() // prev timestamp
+	_ = c.signed()   // prev timestamp delta
+	_ = c.unsigned() // prev value
+	_ = c.unsigned() // prev leading
+	_ = c.unsigned() // prev trailing
+	data := c.string()
+	if c.err != nil || count == 0 {
+		return nil
+	}
+	samples, err := decodeGorilla(data, bits, count, baseTimestamp, baseValue)
+	if err != nil {
+		return err
+	}
+	s.samples = append(s.samples, samples...)
+	return nil
+}
+
+// tsdbTimestampBits are the widths of a delta of delta of timestamps, selected
+// by the number of leading 1 bits, 6 bits of 1 mean a 64 bits delta of delta.
+var tsdbTimestampBits = []uint{5, 8, 11, 14, 32, 64}
+
+// gorillaReader reads the bits of a compressed chunk. The chunk is a buffer of
+// little endian uint64, the bits are appended from the lowest bit of a word.
+type gorillaReader struct {
+	data string
+	pos  uint64
+	bits uint64
+	err  error
+}
+
+func (r *gorillaReader) read(n uint) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos+uint64(n) > r.bits {
+		r.err = fmt.Errorf("read %d bits at %d, beyond %d bits", n, r.pos, r.bits)
+		return 0
+	}
+	var v uint64
+	for i := uint(0); i < n; i++ {
+		bit := uint64(r.data[r.pos/8]>>(r.pos%8)) & 1
+		v |= bit << i
+		r.pos++
+	}
+	return v
+}
+
+// decodeGorilla decodes the samples of a compressed chunk. The first sample
+// is the base timestamp and value, each following sample is a delta of delta
+// of the timestamp and a xor of the value with the previous value.
+func decodeGorilla(data string, bits uint64, count uint64, baseTimestamp uint64, baseValue uint64) ([]string, error) {
+	if bits > uint64(len(data))*8 {
+		return nil, fmt.Errorf("%d bits are used in a chunk of %d bytes", bits, len(data))
+	}
+	r := &gorillaReader{data: data, bits: bits}
+	timestamp, value := baseTimestamp, baseValue
+	var delta int64
+	leading, trailing := uint(32), uint(32)
+	samples := make([]string, 0, 2*count)
+	samples = append(samples, strconv.FormatUint(timestamp, 10), strconv.FormatFloat(math.Float64frombits(value), 'f', -1, 64))
+	for i := uint64(1); i < count && r.err == nil; i++ {
+		var dod int64
+		if r.read(1) == 1 {
+			level := 0
+			for level < len(tsdbTimestampBits)-1 && r.read(1) == 1 {
+				level++
+			}
+			n := tsdbTimestampBits[level]
+			dod = int64(r.read(n))
+			if n < 64 && dod&(1<<(n-1)) != 0 {
+				dod -= 1 << n // sign extension
+			}
+		}
+		delta += dod
+		timestamp += uint64(delta)
+
+		if r.read(1) == 1 {
+			if r.read(1) == 1 {
+				leading = uint(r.read(5))
+				blockSize := uint(r.read(6)) + 1
+				if leading+blockSize > 64 {
+					return nil, fmt.Errorf("sample %d: block of %d bits after %d leading zeros", i, blockSize, leading)
+				}
+				trailing = 64 - leading - blockSize
+			}
+			value ^= r.read(64-leading-trailing) << trailing
+		}
+		samples = append(samples, strconv.FormatUint(timestamp, 10), strconv.FormatFloat(math.Float64frombits(value), 'f', -1, 64))
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.pos != bits {
+		return nil, fmt.Errorf("%d of %d bits are used by %d samples", r.pos, bits, count)
+	}
+	return samples, nil
+}
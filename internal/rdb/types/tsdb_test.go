@@ -0,0 +1,419 @@
+package types
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// tsdbSeriesFixture writes a series as series_rdb_save of RedisTimeSeries.
+type tsdbSeriesFixture struct {
+	encver          int
+	options         uint64
+	duplicatePolicy uint64
+	totalSamples    uint64
+	srcKey          string
+	labels          []string
+	rules           []tsdbRule
+	chunks          func(w *valueWriter)
+}
+
+func (f *tsdbSeriesFixture) save(w *valueWriter) {
+	w.string("ts")
+	w.unsigned(86400000) // retention
+	w.unsigned(4096)     // chunk size
+	w.unsigned(f.options)
+	w.unsigned(3000) // last timestamp
+	w.double(3.5)    // last value
+	w.unsigned(f.totalSamples)
+	w.unsigned(f.duplicatePolicy)
+	if f.srcKey != "" {
+		w.unsigned(1)
+		w.string(f.srcKey)
+	} else {
+		w.unsigned(0)
+	}
+	w.unsigned(uint64(len(f.labels) / 2))
+	for _, label := range f.labels {
+		w.string(label)
+	}
+	w.unsigned(uint64(len(f.rules)))
+	for _, rule := range f.rules {
+		w.string(rule.destKey)
+		w.unsigned(rule.bucketDuration)
+		if f.encver >= tsdbAlignmentEncver {
+			w.unsigned(rule.alignment)
+		}
+		w.unsigned(rule.aggregation)
+		w.unsigned(0) // start of current bucket
+		name := ""
+		if rule.aggregation < uint64(len(tsdbAggregations)) {
+			name = tsdbAggregations[rule.aggregation]
+		}
+		switch name {
+		case "min", "max", "range":
+			w.double(1) // min
+			w.double(7) // max
+			w.unsigned(0)
+		case "avg":
+			w.double(7) // sum
+			w.double(2) // count
+			if f.encver >= tsdbOverflowEncver {
+				w.unsigned(0)
+			}
+		case "std.p", "std.s", "var.p", "var.s":
+			w.double(7)  // sum
+			w.double(25) // sum of squares
+			w.unsigned(2)
+		default:
+			w.double(7)
+			w.unsigned(0)
+		}
+	}
+	f.chunks(w)
+}
+
+// tsdbUncompressedChunk writes an uncompressed chunk of the samples,
+// [timestamp1, value1, timestamp2, value2, ...], with room for one more.
+func tsdbUncompressedChunk(w *valueWriter, samples ...float64) {
+	data := make([]byte, len(samples)*8+16)
+	for i := 0; i < len(samples); i += 2 {
+		binary.LittleEndian.PutUint64(data[i*8:], uint64(samples[i]))
+		binary.LittleEndian.PutUint64(data[i*8+8:], math.Float64bits(samples[i+1]))
+	}
+	w.unsigned(uint64(samples[0])) // base timestamp
+	w.unsigned(uint64(len(samples) / 2))
+	w.unsigned(uint64(len(data)))
+	w.string(string(data))
+}
+
+func parseTSDB(t *testing.T, f *tsdbSeriesFixture) *objectIterator {
+	t.Helper()
+	return parseModule(t, "TSDB-TYPE", f.encver, "ts", f.save)
+}
+
+func TestTSDBObjectUncompressed(t *testing.T) {
+	it := parseTSDB(t, &tsdbSeriesFixture{
+		encver:          tsdbAlignmentEncver,
+		options:         tsdbOptUncompressed,
+		duplicatePolicy: 2,
+		totalSamples:    3,
+		srcKey:          "raw",
+		labels:          []string{"city", "hz", "sensor", "1"},
+		rules: []tsdbRule{
+			{destKey: "ts_avg", bucketDuration: 60000, alignment: 1000, aggregation: 4},
+			{destKey: "ts_max", bucketDuration: 3600000, aggregation: 2},
+		},
+		chunks: func(w *valueWriter) {
+			w.unsigned(2)
+			tsdbUncompressedChunk(w, 1000, 1.5, 2000, -2)
+			tsdbUncompressedChunk(w, 3000, 3.5)
+		},
+	})
+	want := []RedisCmd{
+		{"TS.CREATE", "ts", "RETENTION", "86400000", "CHUNK_SIZE", "4096", "ENCODING", "UNCOMPRESSED",
+			"DUPLICATE_POLICY", "last", "LABELS", "city", "hz", "sensor", "1"},
+		{"TS.MADD", "ts", "1000", "1.5", "ts", "2000", "-2", "ts", "3000", "3.5"},
+		{"TS.CREATERULE", "ts", "ts_avg", "AGGREGATION", "avg", "60000", "1000"},
+		{"TS.CREATERULE", "ts", "ts_max", "AGGREGATION", "max", "3600000"},
+	}
+	got := it.Next()
+	if !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Fatalf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+	for _, cmd := range got {
+		if IsDeferredCmd(cmd) != (cmd[0] == "TS.CREATERULE") {
+			t.Errorf("IsDeferredCmd(%q) = %v", cmd, IsDeferredCmd(cmd))
+		}
+	}
+}
+
+func TestTSDBObjectWithoutAlignment(t *testing.T) {
+	it := parseTSDB(t, &tsdbSeriesFixture{
+		encver:       tsdbAlignmentEncver - 1,
+		options:      tsdbOptUncompressed,
+		totalSamples: 1,
+		rules:        []tsdbRule{{destKey: "ts_sum", bucketDuration: 1000, aggregation: 3}},
+		chunks: func(w *valueWriter) {
+			w.unsigned(1)
+			tsdbUncompressedChunk(w, 1000, 1)
+		},
+	})
+	want := []RedisCmd{
+		{"TS.CREATE", "ts", "RETENTION", "86400000", "CHUNK_SIZE", "4096", "ENCODING", "UNCOMPRESSED"},
+		{"TS.MADD", "ts", "1000", "1"},
+		{"TS.CREATERULE", "ts", "ts_sum", "AGGREGATION", "sum", "1000"},
+	}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+}
+
+func TestTSDBObjectMaddBatch(t *testing.T) {
+	var samples []float64
+	for i := 0; i <= tsdbMaddBatchSize; i++ {
+		samples = append(samples, float64(i+1), float64(i))
+	}
+	it := parseTSDB(t, &tsdbSeriesFixture{
+		encver:       tsdbAlignmentEncver,
+		options:      tsdbOptUncompressed,
+		totalSamples: uint64(len(samples) / 2),
+		chunks: func(w *valueWriter) {
+			w.unsigned(1)
+			tsdbUncompressedChunk(w, samples...)
+		},
+	})
+	got := it.Next()
+	if it.Err() != nil || len(got) != 3 {
+		t.Fatalf("got %d commands, error=[%v], want TS.CREATE and 2 TS.MADD", len(got), it.Err())
+	}
+	if len(got[1]) != 1+3*tsdbMaddBatchSize || len(got[2]) != 1+3 {
+		t.Errorf("got TS.MADD of %d and %d args", len(got[1]), len(got[2]))
+	}
+	last := strconv.Itoa(tsdbMaddBatchSize + 1)
+	if want := (RedisCmd{"TS.MADD", "ts", last, strconv.Itoa(tsdbMaddBatchSize)}); !reflect.DeepEqual(got[2], want) {
+		t.Errorf("got %q, want %q", got[2], want)
+	}
+}
+
+// gorillaWriter appends bits as the Gorilla compression of RedisTimeSeries.
+type gorillaWriter struct {
+	data []byte
+	bits uint64
+}
+
+func (g *gorillaWriter) write(v uint64, n uint) {
+	for i := uint(0); i < n; i++ {
+		if g.bits/8 == uint64(len(g.data)) {
+			g.data = append(g.data, make([]byte, 8)...)
+		}
+		g.data[g.bits/8] |= byte((v>>i)&1) << (g.bits % 8)
+		g.bits++
+	}
+}
+
+// tsdbCompressedChunk writes a compressed chunk of the samples as
+// Compressed_SaveToRDB, [timestamp1, value1, timestamp2, value2, ...].
+func tsdbCompressedChunk(w *valueWriter, samples ...float64) {
+	g := &gorillaWriter{data: make([]byte, 8)}
+	var prevTimestamp, prevValue uint64
+	var prevDelta int64
+	prevLeading, prevTrailing := uint(32), uint(32)
+	for i := 0; i < len(samples); i += 2 {
+		timestamp, value := uint64(samples[i]), math.Float64bits(samples[i+1])
+		if i > 0 {
+			delta := int64(timestamp - prevTimestamp)
+			dod := delta - prevDelta
+			prevDelta = delta
+			if dod == 0 {
+				g.write(0, 1)
+			} else {
+				level := 0
+				for ; level < len(tsdbTimestampBits)-1; level++ {
+					n := tsdbTimestampBits[level]
+					if dod >= -(1<<(n-1)) && dod < 1<<(n-1) {
+						break
+					}
+				}
+				g.write(1<<(level+1)-1, uint(level+1))
+				if level < len(tsdbTimestampBits)-1 {
+					g.write(0, 1)
+				}
+				g.write(uint64(dod), tsdbTimestampBits[level])
+			}
+
+			xor := value ^ prevValue
+			if xor == 0 {
+				g.write(0, 1)
+			} else {
+				leading, trailing := uint(bits.LeadingZeros64(xor)), uint(bits.TrailingZeros64(xor))
+				if leading > 31 {
+					leading = 31
+				}
+				if leading >= prevLeading && trailing >= prevTrailing {
+					g.write(0x1, 2)
+					g.write(xor>>prevTrailing, 64-prevLeading-prevTrailing)
+				} else {
+					blockSize := 64 - leading - trailing
+					g.write(0x3, 2)
+					g.write(uint64(leading), 5)
+					g.write(uint64(blockSize-1), 6)
+					g.write(xor>>trailing, blockSize)
+					prevLeading, prevTrailing = leading, trailing
+				}
+			}
+		}
+		prevTimestamp, prevValue = timestamp, value
+	}
+	w.unsigned(uint64(len(g.data))) // size
+	w.unsigned(uint64(len(samples) / 2))
+	w.unsigned(g.bits)
+	if len(samples) == 0 {
+		w.unsigned(0)
+		w.unsigned(0)
+	} else {
+		w.unsigned(math.Float64bits(samples[1])) // base value
+		w.unsigned(uint64(samples[0]))           // base timestamp
+	}
+	w.unsigned(prevTimestamp)
+	w.signed(prevDelta)
+	w.unsigned(prevValue)
+	w.unsigned(uint64(prevLeading))
+	w.unsigned(uint64(prevTrailing))
+	w.string(string(g.data))
+}
+
+func TestTSDBObjectCompressed(t *testing.T) {
+	// an empty series, such as the destination of a rule, is rewritten
+	it := parseTSDB(t, &tsdbSeriesFixture{
+		encver: tsdbAlignmentEncver,
+		rules:  []tsdbRule{{destKey: "ts_min", bucketDuration: 1000, aggregation: 1}},
+		chunks: func(w *valueWriter) {
+			w.unsigned(1)
+			tsdbCompressedChunk(w)
+		},
+	})
+	want := []RedisCmd{
+		{"TS.CREATE", "ts", "RETENTION", "86400000", "CHUNK_SIZE", "4096", "ENCODING", "COMPRESSED"},
+		{"TS.CREATERULE", "ts", "ts_min", "AGGREGATION", "min", "1000"},
+	}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+
+	// deltas of delta of every width, repeated values, values reusing and
+	// changing the xor block
+	samples := []float64{
+		1000, 1.5,
+		2000, 1.5, // delta of delta 1000
+		3000, 1.25, // 0
+		3010, 1.25, // -990
+		3020, 2, // 0
+		3040, -2, // 10
+		3100, 3.5, // 40
+		4100, 0, // 940
+		104100, 1e100, // 99000
+		4294971396, -1e-100, // 4294767296
+		4294971397, 1.75, // -4294967295
+		4294971398, 1.75,
+	}
+	it = parseTSDB(t, &tsdbSeriesFixture{
+		encver:       tsdbAlignmentEncver,
+		totalSamples: uint64(len(samples)/2 + 1),
+		chunks: func(w *valueWriter) {
+			w.unsigned(2)
+			tsdbCompressedChunk(w, samples...)
+			tsdbCompressedChunk(w, 4294971399, 7)
+		},
+	})
+	madd := RedisCmd{"TS.MADD"}
+	for i := 0; i < len(samples); i += 2 {
+		madd = append(madd, "ts", strconv.FormatUint(uint64(samples[i]), 10), strconv.FormatFloat(samples[i+1], 'f', -1, 64))
+	}
+	madd = append(madd, "ts", "4294971399", "7")
+	want = []RedisCmd{
+		{"TS.CREATE", "ts", "RETENTION", "86400000", "CHUNK_SIZE", "4096", "ENCODING", "COMPRESSED"},
+		madd,
+	}
+	if got := it.Next(); !reflect.DeepEqual(got, want) || it.Err() != nil {
+		t.Errorf("got %q, error=[%v], want %q", got, it.Err(), want)
+	}
+}
+
+func TestTSDBObjectRuleContexts(t *testing.T) {
+	var rules []tsdbRule
+	var want []RedisCmd
+	for aggregation := 1; aggregation < len(tsdbAggregations)-1; aggregation++ { // but twa
+		name := tsdbAggregations[aggregation]
+		rules = append(rules, tsdbRule{destKey: "ts_" + name, bucketDuration: 1000, aggregation: uint64(aggregation)})
+		want = append(want, RedisCmd{"TS.CREATERULE", "ts", "ts_" + name, "AGGREGATION", name, "1000"})
+	}
+	for _, encver := range []int{tsdbMinEncver, tsdbOverflowEncver, tsdbAlignmentEncver} {
+		it := parseTSDB(t, &tsdbSeriesFixture{
+			encver:       encver,
+			options:      tsdbOptUncompressed,
+			totalSamples: 1,
+			rules:        rules,
+			chunks: func(w *valueWriter) {
+				w.unsigned(1)
+				tsdbUncompressedChunk(w, 1000, 1)
+			},
+		})
+		got := it.Next()
+		if it.Err() != nil || len(got) != 2+len(want) || !reflect.DeepEqual(got[2:], want) {
+			t.Errorf("encver %d: got %q, error=[%v], want %q", encver, got, it.Err(), want)
+		}
+	}
+}
+
+func TestTSDBObjectError(t *testing.T) {
+	tests := map[string]*tsdbSeriesFixture{
+		"old encver": {
+			encver:  tsdbMinEncver - 1,
+			options: tsdbOptUncompressed,
+			chunks:  func(w *valueWriter) { w.unsigned(0) },
+		},
+		"new encver": {
+			encver:  tsdbMaxEncver + 1,
+			options: tsdbOptUncompressed,
+			chunks:  func(w *valueWriter) { w.unsigned(0) },
+		},
+		"twa context": {
+			encver:  tsdbAlignmentEncver,
+			options: tsdbOptUncompressed,
+			rules:   []tsdbRule{{destKey: "ts_twa", bucketDuration: 1000, aggregation: uint64(len(tsdbAggregations) - 1)}},
+			chunks:  func(w *valueWriter) { w.unsigned(0) },
+		},
+		"unknown aggregation": {
+			encver:  tsdbAlignmentEncver,
+			options: tsdbOptUncompressed,
+			rules:   []tsdbRule{{destKey: "ts_x", bucketDuration: 1000, aggregation: uint64(len(tsdbAggregations))}},
+			chunks:  func(w *valueWriter) { w.unsigned(0) },
+		},
+		"unknown duplicate policy": {
+			encver:          tsdbAlignmentEncver,
+			options:         tsdbOptUncompressed,
+			duplicatePolicy: uint64(len(tsdbDuplicatePolicies)),
+			chunks:          func(w *valueWriter) { w.unsigned(0) },
+		},
+		"chunk too small": {
+			encver:       tsdbAlignmentEncver,
+			options:      tsdbOptUncompressed,
+			totalSamples: 2,
+			chunks: func(w *valueWriter) {
+				w.unsigned(1)
+				w.unsigned(1000)
+				w.unsigned(2)
+				w.unsigned(16)
+				w.string(string(make([]byte, 16)))
+			},
+		},
+		"compressed bits mismatch": {
+			encver:       tsdbAlignmentEncver,
+			totalSamples: 2,
+			chunks: func(w *valueWriter) {
+				w.unsigned(1)
+				w.unsigned(8)    // size
+				w.unsigned(2)    // count
+				w.unsigned(3)    // idx, 2 bits are used by the same timestamp delta and value
+				w.unsigned(0)    // base value
+				w.unsigned(1000) // base timestamp
+				w.unsigned(1000)
+				w.signed(0)
+				w.unsigned(0)
+				w.unsigned(32)
+				w.unsigned(32)
+				w.string(string(make([]byte, 8)))
+			},
+		},
+	}
+	for name, f := range tests {
+		it := parseTSDB(t, f)
+		if got := it.Next(); got != nil || it.Err() == nil {
+			t.Errorf("%s: got %q, error=[%v], want an error", name, got, it.Err())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package types
+
+import "RedisShake/internal/config"
+
+// minRDBVersion is the first RDB version that knows the type, the types of
+// RDB version 1 are known by all versions.
+var minRDBVersion = map[byte]int{
+	rdbTypeString:              1,
+	rdbTypeList:                1,
+	rdbTypeSet:                 1,
+	rdbTypeZSet:                1,
+	rdbTypeHash:                1,
+	rdbTypeHashZipmap:          2, // 2.2
+	rdbTypeListZiplist:         2,
+	rdbTypeSetIntset:           2,
+	rdbTypeZSetZiplist:         2,
+	rdbTypeHashZiplist:         4, // 2.6
+	rdbTypeListQuicklist:       7, // 3.2
+	rdbTypeZSet2:               8, // 4.0
+	rdbTypeModule:              8,
+	rdbTypeModule2:             8,
+	rdbTypeStreamListpacks:     9,  // 5.0
+	rdbTypeHashListpack:        10, // 7.0
+	rdbTypeZSetListpack:        10,
+	rdbTypeListQuicklist2:      10,
+	rdbTypeStreamListpacks2:    10,
+	rdbTypeSetListpack:         11, // 7.2
+	rdbTypeStreamListpacks3:    11,
+	rdbTypeHashMetadataPreGA:   12, // 7.4
+	rdbTypeHashListpackExPreGA: 12,
+	rdbTypeHashMetadata:        12,
+	rdbTypeHashListpackEx:      12,
+}
+
+// RDBVersionOf returns the RDB version written by the redis version, such as
+// 10 for 7.0. It returns 0 if the redis version is unknown.
+func RDBVersionOf(redisVersion float64) int {
+	switch {
+	case redisVersion == 0:
+		return 0
+	case redisVersion >= 7.4:
+		return 12
+	case redisVersion >= 7.2:
+		return 11
+	case redisVersion >= 7.0:
+		return 10
+	case redisVersion >= 5.0:
+		return 9
+	case redisVersion >= 4.0:
+		return 8
+	case redisVersion >= 3.2:
+		return 7
+	default:
+		return 6
+	}
+}
+
+// TargetSupportsType returns false if the target is too old to RESTORE the
+// type, the key should be rewritten by commands then. It returns true if the
+// target version is unknown.
+func TargetSupportsType(typeByte byte) bool {
+	targetRDBVersion := TargetRDBVersion()
+	if targetRDBVersion == 0 {
+		return true
+	}
+	return minRDBVersion[typeByte] <= targetRDBVersion
+}
+
+// TargetRDBVersion returns the RDB version of the target, 0 if unknown.
+func TargetRDBVersion() int {
+	return RDBVersionOf(config.Opt.Target.Version)
+}
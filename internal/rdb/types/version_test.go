@@ -0,0 +1,36 @@
+package types
+
+import (
+	"testing"
+
+	"RedisShake/internal/config"
+)
+
+func TestTargetSupportsType(t *testing.T) {
+	defer func(opt config.ShakeOptions) { config.Opt = opt }(config.Opt)
+	for _, c := range []struct {
+		version   float64
+		supported []byte
+		rewritten []byte
+	}{
+		{0, []byte{rdbTypeHashListpackEx, rdbTypeSetListpack}, nil}, // unknown
+		{2.8, []byte{rdbTypeString, rdbTypeHashZiplist, rdbTypeZSetZiplist}, []byte{rdbTypeListQuicklist, rdbTypeZSet2, rdbTypeModule2}},
+		{3.0, []byte{rdbTypeListZiplist}, []byte{rdbTypeListQuicklist}},
+		{3.2, []byte{rdbTypeListQuicklist}, []byte{rdbTypeZSet2, rdbTypeModule, rdbTypeModule2}},
+		{4.0, []byte{rdbTypeZSet2, rdbTypeModule, rdbTypeModule2}, []byte{rdbTypeStreamListpacks}},
+		{7.2, []byte{rdbTypeSetListpack, rdbTypeStreamListpacks3}, []byte{rdbTypeHashMetadata, rdbTypeHashListpackEx}},
+		{7.4, []byte{rdbTypeHashMetadata, rdbTypeHashListpackEx}, nil},
+	} {
+		config.Opt.Target.Version = c.version
+		for _, typeByte := range c.supported {
+			if !TargetSupportsType(typeByte) {
+				t.Errorf("target %v: type %d is not supported", c.version, typeByte)
+			}
+		}
+		for _, typeByte := range c.rewritten {
+			if TargetSupportsType(typeByte) {
+				t.Errorf("target %v: type %d is supported", c.version, typeByte)
+			}
+		}
+	}
+}
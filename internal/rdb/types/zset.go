@@ -37,9 +37,7 @@ func (o *ZsetObject) readZset(rd io.Reader) {
 	size := int(structure.ReadLength(rd))
 	o.elements = make([]ZSetEntry, size)
 	for i := 0; i < size; i++ {
-		o.elements[i].Member = structure.ReadString(rd)
-		score := structure.ReadFloat(rd)
-		o.elements[i].Score = fmt.Sprintf("%f", score)
+		o.elements[i] = readZsetEntry(rd, rdbTypeZSet)
 	}
 }
 
@@ -47,12 +45,22 @@ func (o *ZsetObject) readZset2(rd io.Reader) {
 	size := int(structure.ReadLength(rd))
 	o.elements = make([]ZSetEntry, size)
 	for i := 0; i < size; i++ {
-		o.elements[i].Member = structure.ReadString(rd)
-		score := structure.ReadDouble(rd)
-		o.elements[i].Score = fmt.Sprintf("%f", score)
+		o.elements[i] = readZsetEntry(rd, rdbTypeZSet2)
 	}
 }
 
+// readZsetEntry reads a member and its score of rdbTypeZSet or rdbTypeZSet2.
+func readZsetEntry(rd io.Reader, typeByte byte) ZSetEntry {
+	var ele ZSetEntry
+	ele.Member = structure.ReadString(rd)
+	if typeByte == rdbTypeZSet2 {
+		ele.Score = fmt.Sprintf("%f", structure.ReadDouble(rd))
+	} else {
+		ele.Score = fmt.Sprintf("%f", structure.ReadFloat(rd))
+	}
+	return ele
+}
+
 func (o *ZsetObject) readZsetZiplist(rd io.Reader) {
 	list := structure.ReadZipList(rd)
 	size := len(list)
@@ -85,5 +93,5 @@ func (o *ZsetObject) Rewrite() []RedisCmd {
 		cmd := RedisCmd{"zadd", o.key, ele.Score, ele.Member}
 		cmds[inx] = cmd
 	}
-	return cmds
+	return batchCmds(cmds)
 }
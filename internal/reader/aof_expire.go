@@ -0,0 +1,128 @@
+package reader
+
+import (
+	"RedisShake/internal/config"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// absoluteExpire rewrites the relative expire commands to PEXPIREAT and SET
+// PXAT. now is the time in milliseconds the command was received from source.
+// Commands that can not be rewritten are returned unchanged.
+func absoluteExpire(argv []string, now int64) []string {
+	cmd := strings.ToUpper(argv[0])
+	switch cmd {
+	case "EXPIRE", "PEXPIRE", "EXPIREAT":
+		if len(argv) < 3 {
+			return argv
+		}
+		unit := map[string]string{"EXPIRE": "EX", "PEXPIRE": "PX", "EXPIREAT": "EXAT"}[cmd]
+		at, ok := pxat(unit, argv[2], now)
+		if !ok {
+			return argv
+		}
+		return append([]string{"PEXPIREAT", argv[1], at}, argv[3:]...) // keep NX|XX|GT|LT
+	}
+
+	// PXAT of SET and GETEX is added in 6.2
+	if config.Opt.Target.Version < 6.2 {
+		return argv
+	}
+	switch cmd {
+	case "SETEX", "PSETEX":
+		if len(argv) != 4 {
+			return argv
+		}
+		unit := "EX"
+		if cmd == "PSETEX" {
+			unit = "PX"
+		}
+		at, ok := pxat(unit, argv[2], now)
+		if !ok {
+			return argv
+		}
+		return []string{"SET", argv[1], argv[3], "PXAT", at}
+	case "SET", "GETEX":
+		first := 3 // options start after the value of SET
+		if cmd == "GETEX" {
+			first = 2
+		}
+		for i := first; i+1 < len(argv); i++ {
+			unit := strings.ToUpper(argv[i])
+			if unit != "EX" && unit != "PX" && unit != "EXAT" {
+				continue
+			}
+			at, ok := pxat(unit, argv[i+1], now)
+			if !ok {
+				return argv
+			}
+			newArgv := make([]string, len(argv))
+			copy(newArgv, argv)
+			newArgv[i] = "PXAT"
+			newArgv[i+1] = at
+			return newArgv
+		}
+	}
+	return argv
+}
+
+// pxat converts the value of EX, PX or EXAT to the absolute time in milliseconds.
+func pxat(unit string, value string, now int64) (string, bool) {
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	switch unit {
+	case "EX":
+		v = now + v*1000
+	case "PX":
+		v = now + v
+	case "EXAT":
+		v = v * 1000
+	}
+	return strconv.FormatInt(v, 10), true
+}
+
+// offsetClock records the time the aof is received at some offsets, so the
+// commands can be converted with the time they are received instead of the
+// time they are sent, which may be much later.
+type offsetClock struct {
+	mu     sync.Mutex
+	points []offsetTime
+}
+
+type offsetTime struct {
+	offset int64
+	ms     int64
+}
+
+// record records that the bytes from offset are received now. It keeps at most
+// one point per 10ms.
+func (c *offsetClock) record(offset int64) {
+	now := time.Now().UnixMilli()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := len(c.points); n > 0 && now-c.points[n-1].ms < 10 {
+		return
+	}
+	c.points = append(c.points, offsetTime{offset: offset, ms: now})
+}
+
+// timeAt returns the time the byte at offset was received, or now if it is
+// unknown. Offsets must not decrease between calls, the points before offset
+// are dropped.
+func (c *offsetClock) timeAt(offset int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := 0
+	for i+1 < len(c.points) && c.points[i+1].offset <= offset {
+		i++
+	}
+	c.points = c.points[i:]
+	if len(c.points) == 0 || c.points[0].offset > offset {
+		return time.Now().UnixMilli()
+	}
+	return c.points[0].ms
+}
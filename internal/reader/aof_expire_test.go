@@ -0,0 +1,100 @@
+package reader
+
+import (
+	"RedisShake/internal/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAbsoluteExpire(t *testing.T) {
+	const now = 1700000000000
+	tests := []struct {
+		argv    string
+		version float64
+		want    string
+	}{
+		{"EXPIRE k 10", 7.0, "PEXPIREAT k 1700000010000"},
+		{"expire k 10 NX", 7.0, "PEXPIREAT k 1700000010000 NX"},
+		{"PEXPIRE k 1500", 7.0, "PEXPIREAT k 1700000001500"},
+		{"EXPIREAT k 1800000000", 7.0, "PEXPIREAT k 1800000000000"},
+		{"PEXPIREAT k 1800000000000", 7.0, "PEXPIREAT k 1800000000000"},
+		{"EXPIRE k abc", 7.0, "EXPIRE k abc"},
+		{"EXPIRE k", 7.0, "EXPIRE k"},
+		{"SETEX k 10 v", 7.0, "SET k v PXAT 1700000010000"},
+		{"PSETEX k 1500 v", 7.0, "SET k v PXAT 1700000001500"},
+		{"SET k v EX 10", 7.0, "SET k v PXAT 1700000010000"},
+		{"SET k v px 1500", 7.0, "SET k v PXAT 1700000001500"},
+		{"SET k v EXAT 1800000000", 7.0, "SET k v PXAT 1800000000000"},
+		{"SET k v PXAT 1800000000000", 7.0, "SET k v PXAT 1800000000000"},
+		{"SET k v NX GET EX 10", 7.0, "SET k v NX GET PXAT 1700000010000"},
+		{"SET k EX", 7.0, "SET k EX"}, // the value is EX
+		{"SET k EX EX 10", 7.0, "SET k EX PXAT 1700000010000"},
+		{"SET k v KEEPTTL", 7.0, "SET k v KEEPTTL"},
+		{"SET k v NX", 7.0, "SET k v NX"},
+		{"SET k v GET", 7.0, "SET k v GET"},
+		{"GETEX k EX 10", 7.0, "GETEX k PXAT 1700000010000"},
+		{"GETEX k PERSIST", 7.0, "GETEX k PERSIST"},
+		{"INCR k", 7.0, "INCR k"},
+		// PXAT of SET and GETEX is added in 6.2, PEXPIREAT is always there
+		{"EXPIRE k 10", 6.0, "PEXPIREAT k 1700000010000"},
+		{"SETEX k 10 v", 6.0, "SETEX k 10 v"},
+		{"PSETEX k 1500 v", 6.0, "PSETEX k 1500 v"},
+		{"SET k v EX 10", 6.0, "SET k v EX 10"},
+		{"GETEX k EX 10", 6.0, "GETEX k EX 10"},
+	}
+	defer func(version float64) { config.Opt.Target.Version = version }(config.Opt.Target.Version)
+	for _, test := range tests {
+		config.Opt.Target.Version = test.version
+		argv := strings.Fields(test.argv)
+		got := absoluteExpire(argv, now)
+		if want := strings.Fields(test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %v, %s: got %v, want %v", test.version, test.argv, got, want)
+		}
+		if strings.Join(argv, " ") != test.argv {
+			t.Errorf("%s: argv is modified to %v", test.argv, argv)
+		}
+	}
+}
+
+func TestOffsetClock(t *testing.T) {
+	c := &offsetClock{points: []offsetTime{{offset: 100, ms: 1000}, {offset: 200, ms: 2000}, {offset: 300, ms: 3000}}}
+	before := time.Now().UnixMilli()
+	if got := c.timeAt(50); got < before {
+		t.Fatalf("offset before the first point: got %d, want now", got)
+	}
+	tests := []struct {
+		offset int64
+		want   int64
+	}{
+		{100, 1000},
+		{150, 1000},
+		{199, 1000},
+		{200, 2000},
+		{250, 2000},
+		{300, 3000},
+		{10000, 3000}, // the last point until a new one is recorded
+	}
+	for _, test := range tests {
+		if got := c.timeAt(test.offset); got != test.want {
+			t.Fatalf("offset %d: got %d, want %d", test.offset, got, test.want)
+		}
+	}
+	if len(c.points) != 1 {
+		t.Fatalf("the points before the offset are not dropped: %v", c.points)
+	}
+
+	// at most one point per 10ms
+	c = new(offsetClock)
+	c.record(0)
+	c.record(10)
+	if len(c.points) != 1 {
+		t.Fatalf("got %d points, want 1", len(c.points))
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.record(20)
+	if len(c.points) != 2 {
+		t.Fatalf("got points %v", c.points)
+	}
+}
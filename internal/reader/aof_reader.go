@@ -0,0 +1,377 @@
+package reader
+
+import (
+	"RedisShake/internal/client"
+	"RedisShake/internal/client/proto"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb"
+	"RedisShake/internal/utils"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+type AOFReaderOptions struct {
+	Filepath      string `mapstructure:"filepath" default:""`
+	StopTimestamp int64  `mapstructure:"stop_timestamp" default:"0"`
+}
+
+type aofReader struct {
+	ch            chan *entry.Entry
+	files         []string // the base file first, then the incr files in order
+	stopTimestamp int64
+	jsonLines     bool // the json format of file_writer
+	dbId          int
+	sentBefore    int64 // bytes of the files read before the current one
+
+	stat struct {
+		Name          string `json:"name"`
+		Status        string `json:"status"`
+		Filepath      string `json:"filepath"`
+		Files         int    `json:"files"`
+		CurrentFile   string `json:"current_file"`
+		FileSizeBytes int64  `json:"file_size_bytes"`
+		FileSizeHuman string `json:"file_size_human"`
+		FileSentBytes int64  `json:"file_sent_bytes"`
+		FileSentHuman string `json:"file_sent_human"`
+		Percent       string `json:"percent"`
+		Timestamp     int64  `json:"timestamp"` // of the last #TS annotation or json line, in seconds
+		Done          bool   `json:"done"`
+	}
+}
+
+// NewAOFReader reads an aof file, or the files of the multi part aof of
+// Redis 7 when filepath is the appendonlydir or the manifest in it.
+func NewAOFReader(opts *AOFReaderOptions) Reader {
+	absolutePath := utils.GetAbsPath(opts.Filepath)
+	return newAOFFilesReader("aof_reader", absolutePath, aofFiles(absolutePath), opts.StopTimestamp)
+}
+
+// newAOFFilesReader reads the files in order, path is only for status.
+func newAOFFilesReader(name string, path string, files []string, stopTimestamp int64) *aofReader {
+	r := new(aofReader)
+	r.stat.Name = name
+	r.stat.Status = "init"
+	r.stat.Filepath = path
+	r.stopTimestamp = stopTimestamp
+	r.files = files
+	r.stat.Files = len(r.files)
+	for _, file := range r.files {
+		r.stat.FileSizeBytes += int64(utils.GetFileSize(file))
+	}
+	r.stat.FileSizeHuman = humanize.Bytes(uint64(r.stat.FileSizeBytes))
+	log.Infof("[%s] files: %v", r.stat.Name, r.files)
+	return r
+}
+
+// aofFiles returns the files to read in order. path is an aof file, an
+// appendonlydir or a manifest.
+func aofFiles(path string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Panicf("stat aof file failed. file_path=[%s], error=[%v]", path, err)
+	}
+	if info.IsDir() {
+		manifests, err := filepath.Glob(filepath.Join(path, "*.manifest"))
+		if err != nil {
+			log.Panicf(err.Error())
+		}
+		if len(manifests) != 1 {
+			log.Panicf("expect one manifest in the appendonlydir. dir=[%s], manifests=%v", path, manifests)
+		}
+		return parseAOFManifest(manifests[0])
+	}
+	if strings.HasSuffix(path, ".manifest") {
+		return parseAOFManifest(path)
+	}
+	return []string{path}
+}
+
+// parseAOFManifest parses the manifest of Redis 7, each line of which is a
+// file like "file appendonly.aof.1.base.rdb seq 1 type b". The history files
+// (type h) are not part of the data and are skipped.
+func parseAOFManifest(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Panicf("read aof manifest failed. file_path=[%s], error=[%v]", path, err)
+	}
+	type aofFile struct {
+		name string
+		seq  int64
+	}
+	var base *aofFile
+	var incrs []aofFile
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields, ok := splitManifestLine(line)
+		if !ok || len(fields)%2 != 0 {
+			log.Panicf("invalid aof manifest line: %s", line)
+		}
+		file := aofFile{}
+		fileType := ""
+		for i := 0; i < len(fields); i += 2 {
+			value := fields[i+1]
+			switch fields[i] {
+			case "file":
+				file.name = value
+			case "seq":
+				if file.seq, err = strconv.ParseInt(value, 10, 64); err != nil {
+					log.Panicf("invalid seq in aof manifest: %s", line)
+				}
+			case "type":
+				fileType = value
+			}
+		}
+		if file.name == "" {
+			log.Panicf("invalid aof manifest line: %s", line)
+		}
+		switch fileType {
+		case "b":
+			base = &file
+		case "i":
+			incrs = append(incrs, file)
+		case "h":
+		default:
+			log.Panicf("invalid file type in aof manifest: %s", line)
+		}
+	}
+	sort.SliceStable(incrs, func(i, j int) bool { return incrs[i].seq < incrs[j].seq })
+
+	dir := filepath.Dir(path)
+	var files []string
+	if base != nil {
+		files = append(files, filepath.Join(dir, base.name))
+	}
+	for _, file := range incrs {
+		files = append(files, filepath.Join(dir, file.name))
+	}
+	if len(files) == 0 {
+		log.Panicf("no aof file in the manifest. file_path=[%s]", path)
+	}
+	return files
+}
+
+// splitManifestLine splits the line by spaces as sdssplitargs of redis. The
+// file name is quoted by sdscatrepr if it contains spaces or special
+// characters, such as file "append only.aof.1.incr.aof".
+func splitManifestLine(line string) ([]string, bool) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields, true
+		}
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end == -1 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+			continue
+		}
+		end := 1
+		for end < len(line) && line[end] != '"' {
+			if line[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(line) {
+			return nil, false
+		}
+		field, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return nil, false
+		}
+		fields = append(fields, field)
+		line = line[end+1:]
+	}
+}
+
+func (r *aofReader) StartRead() chan *entry.Entry {
+	log.Infof("[%s] start read", r.stat.Name)
+	r.ch = make(chan *entry.Entry, 1024)
+
+	go func() {
+		for _, file := range r.files {
+			if !r.readFile(file) {
+				log.Infof("[%s] stop at timestamp [%d]", r.stat.Name, r.stopTimestamp)
+				break
+			}
+			r.sentBefore += int64(utils.GetFileSize(file))
+		}
+		r.stat.Done = true
+		r.stat.Status = fmt.Sprintf("[%s] files synced", r.stat.Name)
+		log.Infof("[%s] files parse done", r.stat.Name)
+		close(r.ch)
+	}()
+
+	return r.ch
+}
+
+func (r *aofReader) updateOffset(offset int64) {
+	sent := r.sentBefore + offset
+	r.stat.FileSentBytes = sent
+	r.stat.FileSentHuman = humanize.Bytes(uint64(sent))
+	r.stat.Percent = fmt.Sprintf("%.2f%%", float64(sent)/float64(r.stat.FileSizeBytes)*100)
+	r.stat.Status = fmt.Sprintf("[%s] file synced: %s", r.stat.Name, r.stat.Percent)
+}
+
+// readFile reads an rdb file, an aof file or an aof file with an rdb
+// preamble. It returns false if it stops at the stop timestamp.
+func (r *aofReader) readFile(path string) bool {
+	log.Infof("[%s] read file [%s]", r.stat.Name, path)
+	r.stat.CurrentFile = path
+	fp, err := os.OpenFile(path, os.O_RDONLY, 0666)
+	if err != nil {
+		log.Panicf("open file failed. file_path=[%s], error=[%s]", path, err)
+	}
+	defer func() {
+		err = fp.Close()
+		if err != nil {
+			log.Panicf("close file failed. file_path=[%s], error=[%s]", path, err)
+		}
+	}()
+
+	if r.jsonLines {
+		return r.readJSONLines(fp)
+	}
+
+	magic := make([]byte, 5)
+	n, _ := io.ReadFull(fp, magic)
+	if _, err = fp.Seek(0, io.SeekStart); err != nil {
+		log.Panicf(err.Error())
+	}
+	var offset int64
+	if bytes.Equal(magic[:n], []byte("REDIS")) {
+		ld := rdb.NewStreamLoader(r.stat.Name, r.updateOffset, fp, r.ch)
+		_ = ld.ParseRDB()
+		r.dbId = 0 // the aof part starts with SELECT
+		offset = ld.RDBBytes()
+		if _, err = fp.Seek(offset, io.SeekStart); err != nil {
+			log.Panicf(err.Error())
+		}
+		log.Infof("[%s] rdb preamble parse done, size=[%d]", r.stat.Name, offset)
+	}
+	return r.readCommands(fp)
+}
+
+// readCommands sends the commands of the aof from the position of fp until
+// the end of the file, or until an annotation after the stop timestamp.
+func (r *aofReader) readCommands(fp *os.File) bool {
+	rd := bufio.NewReader(fp)
+	protoReader := proto.NewReader(rd)
+	for {
+		pos, err := fp.Seek(0, io.SeekCurrent)
+		if err != nil {
+			log.Panicf(err.Error())
+		}
+		// bytes buffered by rd are not parsed yet
+		r.updateOffset(pos - int64(rd.Buffered()))
+
+		b, err := rd.Peek(1)
+		if err == io.EOF {
+			return true
+		} else if err != nil {
+			log.Panicf(err.Error())
+		}
+		// annotation, such as "#TS:1628217470" of aof-timestamp-enabled
+		if b[0] == '#' {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				log.Panicf("read aof annotation failed. file=[%s], error=[%v]", fp.Name(), err)
+			}
+			line = strings.TrimSpace(line)
+			if ts, ok := strings.CutPrefix(line, "#TS:"); ok {
+				timestamp, err := strconv.ParseInt(ts, 10, 64)
+				if err != nil {
+					log.Panicf("invalid aof timestamp annotation: %s", line)
+				}
+				if r.stopTimestamp != 0 && timestamp > r.stopTimestamp {
+					return false
+				}
+				r.stat.Timestamp = timestamp
+			}
+			continue
+		}
+
+		reply, err := protoReader.ReadReply()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// same as aof-load-truncated of redis
+			log.Warnf("[%s] aof is truncated, the last command is ignored. file=[%s]", r.stat.Name, fp.Name())
+			return true
+		}
+		argv := client.ArrayString(reply, err)
+		if len(argv) == 0 {
+			log.Panicf("invalid aof command. file=[%s]", fp.Name())
+		}
+		if strings.EqualFold(argv[0], "select") {
+			dbId, err := strconv.Atoi(argv[1])
+			if err != nil {
+				log.Panicf(err.Error())
+			}
+			r.dbId = dbId
+			continue
+		}
+
+		e := entry.NewEntry()
+		e.Argv = argv
+		e.DbId = r.dbId
+		r.ch <- e
+	}
+}
+
+// readJSONLines sends the entries of the json format of file_writer until the
+// end of the file, or until an entry after the stop timestamp.
+func (r *aofReader) readJSONLines(fp *os.File) bool {
+	rd := bufio.NewReader(fp)
+	var offset int64
+	for {
+		r.updateOffset(offset)
+		line, err := rd.ReadBytes('\n')
+		offset += int64(len(line))
+		if err == io.EOF {
+			if len(line) != 0 {
+				log.Warnf("[%s] file is truncated, the last line is ignored. file=[%s]", r.stat.Name, fp.Name())
+			}
+			return true
+		} else if err != nil {
+			log.Panicf(err.Error())
+		}
+		e, timestamp, err := entry.ParseJSONLine(line)
+		if err != nil {
+			log.Panicf("[%s] invalid json line. file=[%s], offset=[%d], error=[%v]", r.stat.Name, fp.Name(), offset-int64(len(line)), err)
+		}
+		if r.stopTimestamp != 0 && timestamp/1000 > r.stopTimestamp {
+			return false
+		}
+		r.stat.Timestamp = timestamp / 1000
+		r.ch <- e
+	}
+}
+
+func (r *aofReader) Status() interface{} {
+	return r.stat
+}
+
+func (r *aofReader) StatusString() string {
+	return r.stat.Status
+}
+
+func (r *aofReader) StatusConsistent() bool {
+	return r.stat.Done
+}
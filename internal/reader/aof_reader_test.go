@@ -0,0 +1,144 @@
+package reader
+
+import (
+	"RedisShake/internal/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// aofCommand returns the command in the format of aof.
+func aofCommand(argv ...string) string {
+	s := "*" + strconv.Itoa(len(argv)) + "\r\n"
+	for _, arg := range argv {
+		s += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return s
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseAOFManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "appendonly.aof.manifest")
+	writeTestFile(t, manifest, strings.Join([]string{
+		"file appendonly.aof.1.base.rdb seq 1 type h",
+		"file appendonly.aof.2.base.rdb seq 2 type b",
+		"file appendonly.aof.1.incr.aof seq 1 type h",
+		`file "append only.aof.3.incr.aof" seq 3 type i`,
+		"file appendonly.aof.2.incr.aof seq 2 type i",
+		"",
+	}, "\n"))
+	want := []string{
+		filepath.Join(dir, "appendonly.aof.2.base.rdb"),
+		filepath.Join(dir, "appendonly.aof.2.incr.aof"),
+		filepath.Join(dir, "append only.aof.3.incr.aof"),
+	}
+	if got := parseAOFManifest(manifest); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the appendonlydir and the manifest in it
+	for _, path := range []string{dir, manifest} {
+		if got := aofFiles(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	// a plain aof file
+	writeTestFile(t, want[1], "")
+	if got := aofFiles(want[1]); !reflect.DeepEqual(got, want[1:2]) {
+		t.Errorf("got %q, want %q", got, want[1:2])
+	}
+}
+
+func TestSplitManifestLine(t *testing.T) {
+	tests := map[string][]string{
+		"file a.aof seq 1 type i":   {"file", "a.aof", "seq", "1", "type", "i"},
+		"  file\ta.aof  seq 1 ":     {"file", "a.aof", "seq", "1"},
+		`file "a b.aof" seq 1`:      {"file", "a b.aof", "seq", "1"},
+		`file "a\"b\x01.aof" seq 1`: {"file", "a\"b\x01.aof", "seq", "1"},
+		`file "a.aof`:               nil,
+		`file "a.aof\"`:             nil,
+		`file "a.aof\q"`:            nil,
+	}
+	for line, want := range tests {
+		got, ok := splitManifestLine(line)
+		if !reflect.DeepEqual(got, want) || ok != (want != nil) {
+			t.Errorf("%q: got %q, %v, want %q", line, got, ok, want)
+		}
+	}
+}
+
+func TestParseAOFManifestIncrOnly(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "appendonly.aof.manifest")
+	writeTestFile(t, manifest, "file appendonly.aof.1.incr.aof seq 1 type i\n")
+	want := []string{filepath.Join(dir, "appendonly.aof.1.incr.aof")}
+	if got := parseAOFManifest(manifest); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// aofTestRDB returns an rdb of version 11 with a string key and the checksum
+// disabled, as the preamble of an aof.
+func aofTestRDB() string {
+	return "REDIS0011" +
+		"\xfa\x09redis-ver\x057.2.4" + // aux
+		"\x00\x01k\x01v" + // string k
+		"\xff" + strings.Repeat("\x00", 8)
+}
+
+func readAOFTestFiles(t *testing.T, files []string, stopTimestamp int64) ([]string, *aofReader) {
+	t.Helper()
+	r := newAOFFilesReader("aof_reader", files[0], files, stopTimestamp)
+	var got []string
+	for e := range r.StartRead() {
+		got = append(got, strconv.Itoa(e.DbId)+" "+strings.Join(e.Argv, " "))
+	}
+	return got, r
+}
+
+func TestAOFReaderFiles(t *testing.T) {
+	defer func(opt config.AdvancedOptions) { config.Opt.Advanced = opt }(config.Opt.Advanced)
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = 1024
+	config.Opt.Advanced.RewriteBatchCount = 1
+	config.Opt.Advanced.ForceRewrite = true
+	config.Opt.Advanced.RDBRestoreCommandBehavior = "rewrite"
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "appendonly.aof.1.base.aof")
+	incr := filepath.Join(dir, "appendonly.aof.1.incr.aof")
+	// the commands follow the rdb preamble
+	writeTestFile(t, base, aofTestRDB()+aofCommand("SELECT", "1")+aofCommand("SET", "a", "1"))
+	truncated := "*3\r\n$3\r\nSET\r\n$1\r\nd"
+	writeTestFile(t, incr, "#TS:100\r\n"+aofCommand("SET", "b", "2")+
+		"#TS:200\r\n"+aofCommand("SELECT", "2")+aofCommand("SET", "c", "3")+truncated)
+	files := []string{base, incr}
+
+	got, r := readAOFTestFiles(t, files, 0)
+	want := []string{"0 DEL k", "0 set k v", "1 SET a 1", "1 SET b 2", "2 SET c 3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// the truncated command is ignored
+	if !r.stat.Done || r.stat.Timestamp != 200 || r.stat.FileSentBytes != r.stat.FileSizeBytes-int64(len(truncated)) {
+		t.Errorf("got done=%v, timestamp=%d, sent %d of %d bytes",
+			r.stat.Done, r.stat.Timestamp, r.stat.FileSentBytes, r.stat.FileSizeBytes)
+	}
+
+	// stop before the commands after the timestamp
+	got, r = readAOFTestFiles(t, files, 150)
+	if want := want[:4]; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop at 150: got %q, want %q", got, want)
+	}
+	if r.stat.Timestamp != 100 {
+		t.Errorf("stop at 150: got timestamp %d", r.stat.Timestamp)
+	}
+}
@@ -0,0 +1,28 @@
+package reader
+
+import (
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/utils"
+	"RedisShake/internal/utils/file_rotate"
+)
+
+type FileReaderOptions struct {
+	Dir           string `mapstructure:"dir" default:""`
+	Format        string `mapstructure:"format" default:"resp"` // resp or json
+	StopTimestamp int64  `mapstructure:"stop_timestamp" default:"0"`
+}
+
+// NewFileReader replays the files written by file_writer in the order they
+// are written. The files of the resp format are aof files, so they are read
+// the same as aof_reader.
+func NewFileReader(opts *FileReaderOptions) Reader {
+	dir := utils.GetAbsPath(opts.Dir)
+	files := rotate.ListFiles(dir, entry.FileSuffix(opts.Format))
+	if len(files) == 0 {
+		log.Panicf("no file of file_writer in the dir. dir=[%s], format=[%s]", dir, opts.Format)
+	}
+	r := newAOFFilesReader("file_reader", dir, files, opts.StopTimestamp)
+	r.jsonLines = opts.Format == "json"
+	return r
+}
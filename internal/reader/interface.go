@@ -9,3 +9,13 @@ type Reader interface {
 	status.Statusable
 	StartRead() chan *entry.Entry
 }
+
+// MayDuplicateKeys returns true if the reader may send a key more than once,
+// such as the keys returned twice by SCAN, or a key in more than one rdb file.
+func MayDuplicateKeys(r Reader) bool {
+	switch r.(type) {
+	case *scanStandaloneReader, *scanClusterReader, *rdbFilesReader:
+		return true
+	}
+	return false
+}
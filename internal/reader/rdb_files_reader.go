@@ -0,0 +1,62 @@
+package reader
+
+import (
+	"RedisShake/internal/entry"
+	"fmt"
+	"sync"
+)
+
+// rdbFilesReader reads the rdb files into the same channel, at most parallel
+// files at the same time in the order of the files.
+type rdbFilesReader struct {
+	readers  []*rdbReader
+	parallel int
+	statusId int
+}
+
+func (rd *rdbFilesReader) StartRead() chan *entry.Entry {
+	ch := make(chan *entry.Entry, 1024)
+	tokens := make(chan struct{}, rd.parallel)
+	go func() {
+		var wg sync.WaitGroup
+		for _, r := range rd.readers {
+			tokens <- struct{}{}
+			wg.Add(1)
+			go func(r Reader) {
+				defer func() {
+					<-tokens
+					wg.Done()
+				}()
+				for e := range r.StartRead() {
+					ch <- e
+				}
+			}(r)
+		}
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+func (rd *rdbFilesReader) Status() interface{} {
+	stat := make([]interface{}, 0)
+	for _, r := range rd.readers {
+		stat = append(stat, r.Status())
+	}
+	return stat
+}
+
+func (rd *rdbFilesReader) StatusString() string {
+	rd.statusId += 1
+	rd.statusId %= len(rd.readers)
+	return fmt.Sprintf("file-%d, %s", rd.statusId, rd.readers[rd.statusId].StatusString())
+}
+
+func (rd *rdbFilesReader) StatusConsistent() bool {
+	for _, r := range rd.readers {
+		if !r.StatusConsistent() {
+			return false
+		}
+	}
+	return true
+}
@@ -5,53 +5,244 @@ import (
 	"RedisShake/internal/log"
 	"RedisShake/internal/rdb"
 	"RedisShake/internal/utils"
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
 type RdbReaderOptions struct {
-	Filepath string `mapstructure:"filepath" default:""`
+	Filepath  string   `mapstructure:"filepath" default:""`  // a file, a directory or a glob, "-" for stdin
+	Filepaths []string `mapstructure:"filepaths"`            // more files, such as one rdb per shard
+	Parallel  int      `mapstructure:"parallel" default:"1"` // files loaded at the same time, 0 for all
 }
 
 type rdbReader struct {
-	ch chan *entry.Entry
+	ch        chan *entry.Entry
+	fileBytes int64 // bytes read from the file, compressed if the file is compressed
 
 	stat struct {
 		Name          string `json:"name"`
 		Status        string `json:"status"`
 		Filepath      string `json:"filepath"`
-		FileSizeBytes int64  `json:"file_size_bytes"`
+		Compression   string `json:"compression"`     // gzip, zstd, lz4 or none
+		FileSizeBytes int64  `json:"file_size_bytes"` // 0 if unknown, such as stdin
 		FileSizeHuman string `json:"file_size_human"`
 		FileSentBytes int64  `json:"file_sent_bytes"`
 		FileSentHuman string `json:"file_sent_human"`
+		RdbSentBytes  int64  `json:"rdb_sent_bytes"` // uncompressed
+		RdbSentHuman  string `json:"rdb_sent_human"`
 		Percent       string `json:"percent"`
+		Done          bool   `json:"done"`
 	}
 }
 
+// NewRDBReader returns a reader of the rdb file, or a reader of all the files
+// if there are more than one.
 func NewRDBReader(opts *RdbReaderOptions) Reader {
-	absolutePath := utils.GetAbsPath(opts.Filepath)
+	paths := rdbFilepaths(opts)
+	if len(paths) == 1 {
+		return newRDBFileReader("rdb_reader", paths[0])
+	}
+	rd := &rdbFilesReader{parallel: opts.Parallel}
+	if rd.parallel <= 0 || rd.parallel > len(paths) {
+		rd.parallel = len(paths)
+	}
+	for i, path := range paths {
+		rd.readers = append(rd.readers, newRDBFileReader(fmt.Sprintf("rdb_reader_%d", i), path))
+	}
+	return rd
+}
+
+func newRDBFileReader(name string, path string) *rdbReader {
 	r := new(rdbReader)
-	r.stat.Name = "rdb_reader"
+	r.stat.Name = name
 	r.stat.Status = "init"
-	r.stat.Filepath = absolutePath
-	r.stat.FileSizeBytes = int64(utils.GetFileSize(absolutePath))
+	r.stat.Filepath = path
+	if path != "-" {
+		r.stat.FileSizeBytes = int64(utils.GetFileSize(path))
+	}
 	r.stat.FileSizeHuman = humanize.Bytes(uint64(r.stat.FileSizeBytes))
 	return r
 }
 
+// rdbFilepaths returns the absolute paths of the files in the options, the
+// files of a directory and the matches of a glob are sorted by name. Only the
+// rdb files of a directory are read, see isRDBFile.
+func rdbFilepaths(opts *RdbReaderOptions) []string {
+	var patterns []string
+	if opts.Filepath != "" {
+		patterns = append(patterns, opts.Filepath)
+	}
+	patterns = append(patterns, opts.Filepaths...)
+	if len(patterns) == 1 && patterns[0] == "-" {
+		return patterns
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			log.Panicf("stdin can not be read with other rdb files")
+		}
+		absolutePath := utils.GetAbsPath(pattern)
+		if strings.ContainsAny(pattern, "*?[") {
+			matches, err := filepath.Glob(absolutePath)
+			if err != nil {
+				log.Panicf("invalid rdb file glob. pattern=[%s], error=[%v]", pattern, err)
+			}
+			if len(matches) == 0 {
+				log.Panicf("no rdb file matches the glob. pattern=[%s]", pattern)
+			}
+			paths = append(paths, matches...) // sorted by Glob
+			continue
+		}
+		info, err := os.Stat(absolutePath)
+		if err != nil {
+			log.Panicf("stat rdb file failed. file_path=[%s], error=[%v]", absolutePath, err)
+		}
+		if !info.IsDir() {
+			paths = append(paths, absolutePath)
+			continue
+		}
+		entries, err := os.ReadDir(absolutePath) // sorted by name
+		if err != nil {
+			log.Panicf("read rdb directory failed. dir=[%s], error=[%v]", absolutePath, err)
+		}
+		for _, dirEntry := range entries {
+			if !dirEntry.Type().IsRegular() || strings.HasPrefix(dirEntry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(absolutePath, dirEntry.Name())
+			if !isRDBFile(path) {
+				log.Warnf("skip the file that is not an rdb file in the rdb directory. file_path=[%s]", path)
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		log.Panicf("no rdb file found. filepath=[%s], filepaths=%v", opts.Filepath, opts.Filepaths)
+	}
+	return paths
+}
+
+// isRDBFile returns true if the file starts with the magic string of rdb or
+// the magic bytes of a supported compression.
+func isRDBFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Panicf("open file failed. file_path=[%s], error=[%s]", path, err)
+	}
+	defer file.Close()
+	magic := make([]byte, 5)
+	n, _ := io.ReadFull(file, magic)
+	return bytes.Equal(magic[:n], []byte("REDIS")) || compressionOf(magic[:n]) != "none"
+}
+
+// compressionOf detects the compression of the file by its first bytes.
+func compressionOf(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	case bytes.HasPrefix(magic, []byte{0x04, 0x22, 0x4d, 0x18}):
+		return "lz4"
+	}
+	return "none"
+}
+
+// fileCounter counts the bytes read from the file, the progress of a
+// compressed rdb is reported by them since its uncompressed size is unknown.
+type fileCounter struct {
+	rd io.Reader
+	n  *int64
+}
+
+func (c *fileCounter) Read(p []byte) (int, error) {
+	n, err := c.rd.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// openRDB opens the file, or stdin for "-", and detects the compression by
+// the magic bytes. The returned reader reads the uncompressed rdb.
+func (r *rdbReader) openRDB() (io.Reader, func()) {
+	file := os.Stdin
+	if r.stat.Filepath != "-" {
+		var err error
+		file, err = os.OpenFile(r.stat.Filepath, os.O_RDONLY, 0666)
+		if err != nil {
+			log.Panicf("open file failed. file_path=[%s], error=[%s]", r.stat.Filepath, err)
+		}
+	}
+	closeFile := func() {
+		if err := file.Close(); err != nil {
+			log.Panicf("close file failed. file_path=[%s], error=[%s]", r.stat.Filepath, err)
+		}
+	}
+	bufRd := bufio.NewReader(&fileCounter{rd: file, n: &r.fileBytes})
+	magic, _ := bufRd.Peek(4) // shorter if the file is too small, then it is reported by the loader
+
+	r.stat.Compression = compressionOf(magic)
+	switch r.stat.Compression {
+	case "gzip":
+		gzipRd, err := gzip.NewReader(bufRd)
+		if err != nil {
+			log.Panicf("read gzip file failed. file_path=[%s], error=[%s]", r.stat.Filepath, err)
+		}
+		return gzipRd, closeFile
+	case "zstd":
+		zstdRd, err := zstd.NewReader(bufRd)
+		if err != nil {
+			log.Panicf("read zstd file failed. file_path=[%s], error=[%s]", r.stat.Filepath, err)
+		}
+		return zstdRd, func() {
+			zstdRd.Close()
+			closeFile()
+		}
+	case "lz4":
+		return lz4.NewReader(bufRd), closeFile
+	}
+	return bufRd, closeFile
+}
+
 func (r *rdbReader) StartRead() chan *entry.Entry {
 	log.Infof("[%s] start read", r.stat.Name)
 	r.ch = make(chan *entry.Entry, 1024)
+	src, closeFunc := r.openRDB()
+	log.Infof("[%s] rdb file compression: %s", r.stat.Name, r.stat.Compression)
 	updateFunc := func(offset int64) {
-		r.stat.FileSentBytes = offset
-		r.stat.FileSentHuman = humanize.Bytes(uint64(offset))
-		r.stat.Percent = fmt.Sprintf("%.2f%%", float64(offset)/float64(r.stat.FileSizeBytes)*100)
+		r.stat.RdbSentBytes = offset
+		r.stat.RdbSentHuman = humanize.Bytes(uint64(offset))
+		if r.stat.Compression == "none" {
+			r.stat.FileSentBytes = offset
+		} else {
+			r.stat.FileSentBytes = r.fileBytes
+		}
+		r.stat.FileSentHuman = humanize.Bytes(uint64(r.stat.FileSentBytes))
+		if r.stat.FileSizeBytes == 0 {
+			r.stat.Percent = "unknown"
+			r.stat.Status = fmt.Sprintf("[%s] rdb file synced: %s", r.stat.Name, r.stat.RdbSentHuman)
+			return
+		}
+		r.stat.Percent = fmt.Sprintf("%.2f%%", float64(r.stat.FileSentBytes)/float64(r.stat.FileSizeBytes)*100)
 		r.stat.Status = fmt.Sprintf("[%s] rdb file synced: %s", r.stat.Name, r.stat.Percent)
 	}
-	rdbLoader := rdb.NewLoader(r.stat.Name, updateFunc, r.stat.Filepath, r.ch)
+	rdbLoader := rdb.NewStreamLoader(r.stat.Name, updateFunc, src, r.ch)
 
 	go func() {
 		_ = rdbLoader.ParseRDB()
+		closeFunc()
+		r.stat.Done = true
 		log.Infof("[%s] rdb file parse done", r.stat.Name)
 		close(r.ch)
 	}()
@@ -68,5 +259,5 @@ func (r *rdbReader) StatusString() string {
 }
 
 func (r *rdbReader) StatusConsistent() bool {
-	return r.stat.FileSentBytes == r.stat.FileSizeBytes
+	return r.stat.Done
 }
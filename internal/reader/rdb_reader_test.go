@@ -0,0 +1,74 @@
+package reader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRDBFilepaths(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"shards/2.rdb":     "REDIS0011",
+		"shards/1.rdb.gz":  "\x1f\x8b\x08",
+		"shards/3.zst":     "\x28\xb5\x2f\xfd",
+		"shards/4.rdb.lz4": "\x04\x22\x4d\x18",
+		"shards/.hidden":   "REDIS0011",
+		"shards/README":    "backup of 2024-01-01",
+		"shards/empty.rdb": "",
+		"more/b.rdb":       "REDIS0011",
+		"more/a.rdb":       "REDIS0011",
+		"more/a.aof":       "*1\r\n$4\r\nPING\r\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeTestFile(t, path, content)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "shards", "sub.rdb"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := rdbFilepaths(&RdbReaderOptions{
+		Filepath:  filepath.Join(dir, "shards"),
+		Filepaths: []string{filepath.Join(dir, "more", "*.rdb"), filepath.Join(dir, "more", "a.aof")},
+	})
+	want := []string{
+		// the rdb files of the directory by name
+		filepath.Join(dir, "shards", "1.rdb.gz"),
+		filepath.Join(dir, "shards", "2.rdb"),
+		filepath.Join(dir, "shards", "3.zst"),
+		filepath.Join(dir, "shards", "4.rdb.lz4"),
+		// the matches of the glob by name
+		filepath.Join(dir, "more", "a.rdb"),
+		filepath.Join(dir, "more", "b.rdb"),
+		// a file is read as it is
+		filepath.Join(dir, "more", "a.aof"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := rdbFilepaths(&RdbReaderOptions{Filepath: "-"}); !reflect.DeepEqual(got, []string{"-"}) {
+		t.Errorf("got %q, want stdin", got)
+	}
+}
+
+func TestCompressionOf(t *testing.T) {
+	tests := map[string]string{
+		"\x1f\x8b\x08\x00": "gzip",
+		"\x28\xb5\x2f\xfd": "zstd",
+		"\x04\x22\x4d\x18": "lz4",
+		"REDIS":            "none",
+		"\x28\xb5":         "none",
+		"":                 "none",
+	}
+	for magic, want := range tests {
+		if got := compressionOf([]byte(magic)); got != want {
+			t.Errorf("%q: got %s, want %s", magic, got, want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package reader
+
+import (
+	"RedisShake/internal/client"
+	"RedisShake/internal/client/proto"
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb/types"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const fetchByTypeBatchSize = 512 // elements per read and per write command
+
+// fetchByType reads the value with the commands of its type instead of DUMP,
+// and sends the commands that create it. It works with the sources and the
+// targets that do not support the DUMP payload of redis.
+func (r *scanStandaloneReader) fetchByType(c *client.Redis, dbId int, key string) {
+	send := func(argv ...string) {
+		e := entry.NewEntry()
+		e.DbId = dbId
+		e.Argv = argv
+		r.ch <- e
+	}
+	keyType := c.DoWithStringReply("TYPE", key)
+	switch keyType {
+	case "none":
+		return // key not exist
+	case "string", "list", "set", "hash", "zset", "stream":
+	default:
+		// the values of modules can only be read by DUMP
+		types.SkipUnrewritableKey(key, fmt.Errorf("the value of type [%s] can not be fetched by commands", keyType))
+		return
+	}
+	if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
+		send("DEL", key)
+	}
+	batch := strconv.Itoa(fetchByTypeBatchSize)
+
+	switch keyType {
+	case "string":
+		value, err := client.String(c.TryDo("GET", key))
+		if err == proto.Nil {
+			return // key not exist
+		} else if err != nil {
+			log.Panicf(err.Error())
+		}
+		send("SET", key, value)
+	case "list":
+		for start := 0; ; start += fetchByTypeBatchSize {
+			elements := client.ArrayString(c.TryDo("LRANGE", key, strconv.Itoa(start), strconv.Itoa(start+fetchByTypeBatchSize-1)))
+			if len(elements) > 0 {
+				send(append([]string{"RPUSH", key}, elements...)...)
+			}
+			if len(elements) < fetchByTypeBatchSize {
+				break
+			}
+		}
+	case "set":
+		scanElements(c, "SSCAN", key, func(elements []string) {
+			send(append([]string{"SADD", key}, elements...)...)
+		})
+	case "hash":
+		scanElements(c, "HSCAN", key, func(elements []string) {
+			send(append([]string{"HSET", key}, elements...)...)
+			r.fetchHashFieldTTL(c, key, elements, send)
+		})
+	case "zset":
+		for start := 0; ; start += fetchByTypeBatchSize {
+			elements := client.ArrayString(c.TryDo("ZRANGE", key, strconv.Itoa(start), strconv.Itoa(start+fetchByTypeBatchSize-1), "WITHSCORES"))
+			argv := []string{"ZADD", key}
+			for i := 0; i+1 < len(elements); i += 2 {
+				argv = append(argv, elements[i+1], elements[i]) // score member
+			}
+			if len(argv) > 2 {
+				send(argv...)
+			}
+			if len(elements) < 2*fetchByTypeBatchSize {
+				break
+			}
+		}
+	case "stream":
+		start := "-"
+		for {
+			reply, err := c.TryDo("XRANGE", key, start, "+", "COUNT", batch)
+			if err != nil {
+				log.Panicf(err.Error())
+			}
+			items := reply.([]interface{})
+			for _, item := range items {
+				id := item.([]interface{})[0].(string)
+				fields := client.ArrayString(item.([]interface{})[1], nil)
+				send(append([]string{"XADD", key, id}, fields...)...)
+				start = nextStreamID(id)
+			}
+			if len(items) < fetchByTypeBatchSize {
+				break
+			}
+		}
+		fetchStreamMeta(c, key, start == "-", send)
+	}
+
+	pttl, err := client.Int64(c.TryDo("PTTL", key))
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	if pttl > 0 {
+		send("PEXPIRE", key, strconv.FormatInt(pttl, 10))
+	}
+}
+
+// fetchHashFieldTTL reads the expire time of the fields by HPEXPIRETIME and
+// sends HPEXPIREAT of them. fields is [field1, value1, field2, value2, ...].
+// The sources older than Redis 7.4 have no field expiration, and the targets
+// older than 7.4 can not set it, see types.TargetSupportsHashFieldTTL.
+func (r *scanStandaloneReader) fetchHashFieldTTL(c *client.Redis, key string, fields []string, send func(argv ...string)) {
+	if r.noHashFieldTTL {
+		return
+	}
+	argv := []string{"HPEXPIRETIME", key, "FIELDS", strconv.Itoa(len(fields) / 2)}
+	for i := 0; i < len(fields); i += 2 {
+		argv = append(argv, fields[i])
+	}
+	reply, err := c.TryDo(argv...)
+	if err != nil {
+		log.Infof("[%s] the source does not support HPEXPIRETIME, the expire time of hash fields is not fetched. error=[%v]", r.stat.Name, err)
+		r.noHashFieldTTL = true
+		return
+	}
+	for i, item := range reply.([]interface{}) {
+		field := fields[i*2]
+		expireAt, _ := client.Int64(item, nil)
+		switch expireAt {
+		case -1: // no expire
+		case -2: // expired after HSCAN
+			send("HDEL", key, field)
+		default:
+			if types.TargetSupportsHashFieldTTL(key) {
+				send("HPEXPIREAT", key, strconv.FormatInt(expireAt, 10), "FIELDS", "1", field)
+			}
+		}
+	}
+}
+
+// fetchStreamMeta sends the last id, the consumer groups and their pending
+// entries of a stream, the same as the rewrite commands of a stream in rdb.
+// Empty consumers are discarded.
+func fetchStreamMeta(c *client.Redis, key string, empty bool, send func(argv ...string)) {
+	info := replyMap(c.TryDo("XINFO", "STREAM", key))
+	lastId, _ := info["last-generated-id"].(string)
+	if empty {
+		// the XADD MAXLEN 0 trick creates an empty stream
+		send("XADD", key, "MAXLEN", "0", lastId, "x", "y")
+	}
+	send("XSETID", key, lastId)
+
+	groups, err := c.TryDo("XINFO", "GROUPS", key)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	for _, item := range groups.([]interface{}) {
+		group := replyMap(item, nil)
+		name, _ := group["name"].(string)
+		lastDeliveredId, _ := group["last-delivered-id"].(string)
+		send("XGROUP", "CREATE", key, name, lastDeliveredId)
+
+		// XPENDING key group start end count: [[id, consumer, idle, delivery count], ...]
+		start := "-"
+		for {
+			reply, err := c.TryDo("XPENDING", key, name, start, "+", strconv.Itoa(fetchByTypeBatchSize))
+			if err != nil {
+				log.Panicf(err.Error())
+			}
+			pending := reply.([]interface{})
+			now := time.Now().UnixMilli()
+			for _, p := range pending {
+				pel := p.([]interface{})
+				id := pel[0].(string)
+				consumer := pel[1].(string)
+				idle, _ := client.Int64(pel[2], nil)
+				count, _ := client.Int64(pel[3], nil)
+				send("XCLAIM", key, name, consumer, "0", id,
+					"TIME", strconv.FormatInt(now-idle, 10),
+					"RETRYCOUNT", strconv.FormatInt(count, 10),
+					"JUSTID", "FORCE")
+				start = nextStreamID(id)
+			}
+			if len(pending) < fetchByTypeBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// replyMap converts the reply of field-value pairs, such as XINFO STREAM, to
+// a map.
+func replyMap(reply interface{}, err error) map[string]interface{} {
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	array := reply.([]interface{})
+	m := make(map[string]interface{}, len(array)/2)
+	for i := 0; i+1 < len(array); i += 2 {
+		if k, ok := array[i].(string); ok {
+			m[k] = array[i+1]
+		}
+	}
+	return m
+}
+
+// scanElements iterates the elements of a set or a hash by SSCAN or HSCAN.
+func scanElements(c *client.Redis, cmd string, key string, fn func(elements []string)) {
+	cursor := "0"
+	for {
+		reply, err := c.TryDo(cmd, key, cursor, "COUNT", strconv.Itoa(fetchByTypeBatchSize))
+		if err != nil {
+			log.Panicf(err.Error())
+		}
+		array := reply.([]interface{})
+		cursor = array[0].(string)
+		elements := client.ArrayString(array[1], nil)
+		if len(elements) > 0 {
+			fn(elements)
+		}
+		if cursor == "0" {
+			return
+		}
+	}
+}
+
+// nextStreamID returns the smallest stream id that is larger than id, so
+// XRANGE can continue from it without the exclusive range of Redis 6.2.
+func nextStreamID(id string) string {
+	items := strings.SplitN(id, "-", 2)
+	if len(items) != 2 {
+		log.Panicf("invalid stream id: %s", id)
+	}
+	ms, err1 := strconv.ParseUint(items[0], 10, 64)
+	seq, err2 := strconv.ParseUint(items[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		log.Panicf("invalid stream id: %s", id)
+	}
+	if seq == ^uint64(0) {
+		return strconv.FormatUint(ms+1, 10) + "-0"
+	}
+	return items[0] + "-" + strconv.FormatUint(seq+1, 10)
+}
@@ -8,6 +8,8 @@ import (
 	"RedisShake/internal/log"
 	"RedisShake/internal/rdb/types"
 	"RedisShake/internal/utils"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"math/bits"
 	"regexp"
@@ -23,6 +25,10 @@ type ScanReaderOptions struct {
 	Tls      bool   `mapstructure:"tls" default:"false"`
 	KSN      bool   `mapstructure:"ksn" default:"false"`
 	DBS      []int  `mapstructure:"dbs"`
+
+	// read the values by the commands of their types, such as HSCAN and
+	// LRANGE, instead of DUMP
+	FetchByType bool `mapstructure:"fetch_by_type" default:"false"`
 }
 
 type dbKey struct {
@@ -35,6 +41,9 @@ type scanStandaloneReader struct {
 	opts     *ScanReaderOptions
 	ch       chan *entry.Entry
 	keyQueue *utils.UniqueQueue
+	deferred types.DeferredCmds // sent when the scan is finished
+
+	noHashFieldTTL bool // the source does not support HPEXPIRETIME, see fetch_by_type
 
 	stat struct {
 		Name              string `json:"name"`
@@ -150,58 +159,145 @@ func (r *scanStandaloneReader) fetch() {
 			}
 			nowDbId = dbId
 		}
-		// dump
-		c.Send("DUMP", key)
-		c.Send("PTTL", key)
-		iDump, err1 := c.Receive()
-		iPttl, err2 := c.Receive()
-		if err1 == proto.Nil {
-			continue // key not exist
-		} else if err1 != nil {
-			log.Panicf(err1.Error())
-		} else if err2 != nil {
-			log.Panicf(err2.Error())
-		}
-		dump := iDump.(string)
-		pttl := int(iPttl.(int64))
-		if pttl == -2 {
-			continue // key not exist
-		}
-		if pttl == -1 {
-			pttl = 0 // -1 means no expire
-		}
-		if uint64(len(dump)) > config.Opt.Advanced.TargetRedisProtoMaxBulkLen {
+		if r.opts.FetchByType {
+			r.fetchByType(c, dbId, key)
+		} else {
+			r.fetchByDump(c, dbId, key)
+		}
+		if r.stat.ScanFinished && r.keyQueue.Len() == 0 {
+			r.sendDeferred() // the keys they refer to have been sent
+		}
+	}
+
+	r.sendDeferred()
+	log.Infof("[%s] scanStandaloneReader fetch finished.", r.stat.Name)
+	close(r.ch)
+}
+
+// fetchByDump reads the key by DUMP, and sends RESTORE, or the rewrite
+// commands if the target can not RESTORE it.
+func (r *scanStandaloneReader) fetchByDump(c *client.Redis, dbId int, key string) {
+	// IDLETIME and FREQ are read before DUMP, which touches the key.
+	// Only one of them works depending on the maxmemory-policy of source.
+	withLRU := config.Opt.Target.Version >= 5.0
+	if withLRU {
+		c.Send("OBJECT", "IDLETIME", key)
+		c.Send("OBJECT", "FREQ", key)
+	}
+	// dump
+	c.Send("DUMP", key)
+	c.Send("PTTL", key)
+	var idle, freq int64
+	if withLRU {
+		idle, _ = client.Int64(c.Receive())
+		freq, _ = client.Int64(c.Receive())
+	}
+	iDump, err1 := c.Receive()
+	iPttl, err2 := c.Receive()
+	if err1 == proto.Nil {
+		return // key not exist
+	} else if err1 != nil {
+		log.Panicf(err1.Error())
+	} else if err2 != nil {
+		log.Panicf(err2.Error())
+	}
+	dump := iDump.(string)
+	pttl := int(iPttl.(int64))
+	if pttl == -2 {
+		return // key not exist
+	}
+	if pttl == -1 {
+		pttl = 0 // -1 means no expire
+	}
+	typeByte := dump[0]
+	if types.IsModuleType(typeByte) && config.Opt.Module.UnknownModuleBehavior != "passthrough" {
+		// parse the value to find unknown modules
+		if types.ParseObject(strings.NewReader(dump[1:len(dump)-10]), typeByte, key) == nil {
+			return // skipped
+		}
+	}
+	tooLarge := uint64(len(dump)) > config.Opt.Advanced.TargetRedisProtoMaxBulkLen
+	if tooLarge || !types.TargetSupportsType(typeByte) || config.Opt.Advanced.ForceRewrite {
+		if tooLarge {
 			log.Warnf("key=[%s] dump len=[%d] too large, split it. This is not a good practice in Redis.", key, len(dump))
-			typeByte := dump[0]
-			anotherReader := strings.NewReader(dump[1 : len(dump)-10])
-			o := types.ParseObject(anotherReader, typeByte, key)
-			cmds := o.Rewrite()
+		}
+		anotherReader := strings.NewReader(dump[1 : len(dump)-10])
+		it := types.NewRewriteIterator(anotherReader, typeByte, key)
+		if it == nil {
+			return // skipped
+		}
+		cmds := it.Next()
+		if err := it.Err(); err != nil {
+			types.SkipUnrewritableKey(key, err)
+			return
+		}
+		if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
+			r.ch <- &entry.Entry{
+				DbId: dbId,
+				Argv: []string{"DEL", key},
+			}
+		}
+		for ; cmds != nil; cmds = it.Next() {
 			for _, cmd := range cmds {
+				if r.deferred.Add(dbId, cmd) {
+					continue
+				}
 				e := entry.NewEntry()
 				e.DbId = dbId
 				e.Argv = cmd
 				r.ch <- e
 			}
-			if pttl != 0 {
-				e := entry.NewEntry()
-				e.DbId = dbId
-				e.Argv = []string{"PEXPIRE", key, strconv.Itoa(pttl)}
-				r.ch <- e
-			}
-		} else {
-			argv := []string{"RESTORE", key, strconv.Itoa(pttl), dump}
-			if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
-				argv = append(argv, "replace")
-			}
-			r.ch <- &entry.Entry{
-				DbId: dbId,
-				Argv: argv,
-			}
+		}
+		if pttl != 0 {
+			e := entry.NewEntry()
+			e.DbId = dbId
+			e.Argv = []string{"PEXPIRE", key, strconv.Itoa(pttl)}
+			r.ch <- e
+		}
+	} else {
+		// the target rejects the payload if the version in footer is
+		// newer than its RDB version, even if it knows the type
+		dumpVersion := int(binary.LittleEndian.Uint16([]byte(dump[len(dump)-10 : len(dump)-8])))
+		if targetVersion := types.TargetRDBVersion(); targetVersion != 0 && dumpVersion > targetVersion {
+			dump = refooterDump(dump)
+		}
+		argv := []string{"RESTORE", key, strconv.Itoa(pttl), dump}
+		if config.Opt.Advanced.RDBRestoreCommandBehavior == "rewrite" {
+			argv = append(argv, "replace")
+		}
+		if idle != 0 {
+			argv = append(argv, "idletime", strconv.FormatInt(idle, 10))
+		}
+		if freq != 0 {
+			argv = append(argv, "freq", strconv.FormatInt(freq, 10))
+		}
+		r.ch <- &entry.Entry{
+			DbId: dbId,
+			Argv: argv,
 		}
 	}
+}
 
-	log.Infof("[%s] scanStandaloneReader fetch finished.", r.stat.Name)
-	close(r.ch)
+// sendDeferred sends the deferred commands, such as TS.CREATERULE, once the
+// keys they refer to may have been sent.
+func (r *scanStandaloneReader) sendDeferred() {
+	r.deferred.Flush(func(dbId int, cmd types.RedisCmd) {
+		e := entry.NewEntry()
+		e.DbId = dbId
+		e.Argv = cmd
+		r.ch <- e
+	})
+}
+
+// refooterDump replaces the RDB version in the footer of a DUMP payload with 6,
+// which is accepted by all targets, the same as the payloads created by the
+// rdb loader.
+func refooterDump(dump string) string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(dump[:len(dump)-10])
+	_ = binary.Write(buf, binary.LittleEndian, uint16(6))
+	_ = binary.Write(buf, binary.LittleEndian, utils.CalcCRC64(buf.Bytes()))
+	return buf.String()
 }
 
 func (r *scanStandaloneReader) Status() interface{} {
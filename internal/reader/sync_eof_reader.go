@@ -0,0 +1,66 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// rdbEOFMarkLen is the length of the mark used by diskless replication, which
+// sends the rdb as "$EOF:<mark>\r\n<rdb><mark>" because the size is unknown.
+const rdbEOFMarkLen = 40
+
+// eofMarkReader reads the rdb from rd until the mark. It never consumes the
+// bytes after the mark from rd, they are the beginning of the aof stream.
+type eofMarkReader struct {
+	rd   *bufio.Reader
+	mark []byte
+	tail []byte // bytes that may be the beginning of the mark, not returned yet
+	out  []byte // bytes known to be rdb data, not returned yet
+	done bool
+}
+
+func newEOFMarkReader(rd *bufio.Reader, mark []byte) *eofMarkReader {
+	return &eofMarkReader{rd: rd, mark: mark}
+}
+
+func (e *eofMarkReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		err := e.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+func (e *eofMarkReader) fill() error {
+	_, err := e.rd.Peek(1)
+	if err != nil {
+		return err
+	}
+	data, _ := e.rd.Peek(e.rd.Buffered())
+	window := make([]byte, 0, len(e.tail)+len(data))
+	window = append(window, e.tail...)
+	window = append(window, data...)
+	if i := bytes.Index(window, e.mark); i != -1 {
+		e.out = window[:i]
+		_, err = e.rd.Discard(i + len(e.mark) - len(e.tail))
+		e.tail = nil
+		e.done = true
+		return err
+	}
+	keep := len(e.mark) - 1
+	if keep > len(window) {
+		keep = len(window)
+	}
+	e.out = window[:len(window)-keep]
+	e.tail = window[len(window)-keep:]
+	_, err = e.rd.Discard(len(data))
+	return err
+}
@@ -0,0 +1,64 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// chunkReader returns at most size bytes per Read, so the mark straddles the
+// reads of bufio.Reader.
+type chunkReader struct {
+	rd   io.Reader
+	size int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.size {
+		p = p[:c.size]
+	}
+	return c.rd.Read(p)
+}
+
+func TestEOFMarkReader(t *testing.T) {
+	mark := bytes.Repeat([]byte("0123456789"), 4)[:rdbEOFMarkLen]
+	aof := []byte("*1\r\n$4\r\nPING\r\n")
+	payloads := map[string][]byte{
+		"empty":           {},
+		"shorter":         []byte("REDIS0011"),
+		"one less":        bytes.Repeat([]byte{'x'}, rdbEOFMarkLen-1),
+		"mark length":     bytes.Repeat([]byte{'x'}, rdbEOFMarkLen),
+		"long":            bytes.Repeat([]byte("REDIS0011\xfe\x00"), 1000),
+		"partial mark":    append([]byte("REDIS"), mark[:rdbEOFMarkLen-1]...),
+		"repeated prefix": append(bytes.Repeat(mark[:10], 3), 'x'), // a mark never appears in the rdb
+	}
+	readers := map[string]func(io.Reader) io.Reader{
+		"whole":    func(rd io.Reader) io.Reader { return rd },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"chunk 7":  func(rd io.Reader) io.Reader { return &chunkReader{rd: rd, size: 7} },
+		"chunk 41": func(rd io.Reader) io.Reader { return &chunkReader{rd: rd, size: rdbEOFMarkLen + 1} },
+	}
+	for payloadName, payload := range payloads {
+		for readerName, wrap := range readers {
+			stream := append(append(append([]byte{}, payload...), mark...), aof...)
+			rd := bufio.NewReaderSize(wrap(bytes.NewReader(stream)), 16)
+			got, err := io.ReadAll(newEOFMarkReader(rd, mark))
+			if err != nil {
+				t.Fatalf("%s/%s: %v", payloadName, readerName, err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Fatalf("%s/%s: got %d bytes of rdb, want %d", payloadName, readerName, len(got), len(payload))
+			}
+			rest, err := io.ReadAll(rd)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", payloadName, readerName, err)
+			}
+			if !bytes.Equal(rest, aof) {
+				t.Fatalf("%s/%s: got %q after the mark, want %q", payloadName, readerName, rest, aof)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package reader
 
 import (
 	"RedisShake/internal/client"
+	"RedisShake/internal/client/proto"
 	"RedisShake/internal/config"
 	"RedisShake/internal/entry"
 	"RedisShake/internal/log"
@@ -16,9 +17,18 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// the source pings replicas every 10s by default (repl-ping-replica-period),
+	// so no data for this long means the link is broken
+	aofReadTimeout      = 60 * time.Second
+	reconnectMinBackoff = 1 * time.Second
+	reconnectMaxBackoff = 30 * time.Second
+)
+
 type SyncReaderOptions struct {
 	Cluster  bool   `mapstructure:"cluster" default:"false"`
 	Address  string `mapstructure:"address" default:""`
@@ -27,6 +37,8 @@ type SyncReaderOptions struct {
 	Tls      bool   `mapstructure:"tls" default:"false"`
 	SyncRdb  bool   `mapstructure:"sync_rdb" default:"true"`
 	SyncAof  bool   `mapstructure:"sync_aof" default:"true"`
+	// parse the rdb while it is received instead of saving it to dump.rdb first
+	DisklessLoad bool `mapstructure:"diskless_load" default:"false"`
 }
 
 type State string
@@ -40,14 +52,20 @@ const (
 )
 
 type syncStandaloneReader struct {
-	opts   *SyncReaderOptions
-	client *client.Redis
+	opts       *SyncReaderOptions
+	client     *client.Redis
+	clientLock sync.Mutex // guards client while it is replaced by reconnect
 
 	ch   chan *entry.Entry
 	DbId int
 
 	rd *bufio.Reader
 
+	state   *syncState // saved replication state, nil if not resumable
+	written *writtenOffsets
+
+	aofClock offsetClock // for aof_absolute_expire
+
 	stat struct {
 		Name    string `json:"name"`
 		Address string `json:"address"`
@@ -56,6 +74,10 @@ type syncStandaloneReader struct {
 		// status
 		Status State `json:"status"`
 
+		// replication info
+		MasterReplId string `json:"master_replid"`
+		Resumed      bool   `json:"resumed"` // true if continued from saved state by partial resync
+
 		// rdb info
 		RdbFilePath      string `json:"rdb_file_path"`
 		RdbFileSizeBytes int64  `json:"rdb_file_size_bytes"` // bytes of the rdb file
@@ -70,6 +92,14 @@ type syncStandaloneReader struct {
 		AofSentOffset     int64  `json:"aof_sent_offset"`     // offset of AOF sent to chan
 		AofReceivedBytes  int64  `json:"aof_received_bytes"`  // bytes of AOF received from master
 		AofReceivedHuman  string `json:"aof_received_human"`
+
+		// reconnect info
+		Reconnecting       bool    `json:"reconnecting"`
+		ReconnectAttempts  int64   `json:"reconnect_attempts"` // dial attempts after the link to source broke
+		ReconnectCount     int64   `json:"reconnect_count"`    // successful reconnects
+		LastOutageTime     string  `json:"last_outage_time"`
+		LastOutageSeconds  float64 `json:"last_outage_seconds"`
+		TotalOutageSeconds float64 `json:"total_outage_seconds"`
 	}
 }
 
@@ -82,7 +112,22 @@ func NewSyncStandaloneReader(opts *SyncReaderOptions) Reader {
 	r.stat.Address = opts.Address
 	r.stat.Status = kHandShake
 	r.stat.Dir = utils.GetAbsPath(r.stat.Name)
-	utils.CreateEmptyDir(r.stat.Dir)
+	if opts.SyncAof && utils.IsExist(r.stat.Dir) {
+		r.state = loadSyncState(r.stat.Dir)
+	}
+	if r.state != nil {
+		begin, end, ok := rotate.AOFRange(r.stat.Dir)
+		if !ok || r.state.Offset < begin || r.state.Offset > end {
+			log.Warnf("[%s] aof files do not cover the saved offset, ignore saved sync state. offset=[%d], aof_begin=[%d], aof_end=[%d]", r.stat.Name, r.state.Offset, begin, end)
+			r.state = nil
+		} else {
+			r.stat.AofReceivedOffset = end
+			log.Infof("[%s] found saved sync state. replid=[%s], offset=[%d], aof_end=[%d]", r.stat.Name, r.state.ReplId, r.state.Offset, end)
+		}
+	}
+	if r.state == nil {
+		utils.CreateEmptyDir(r.stat.Dir)
+	}
 	return r
 }
 
@@ -90,12 +135,19 @@ func (r *syncStandaloneReader) StartRead() chan *entry.Entry {
 	r.ch = make(chan *entry.Entry, 1024)
 	go func() {
 		r.sendReplconfListenPort()
-		r.sendPSync()
+		resumed := r.sendPSync()
 		go r.sendReplconfAck() // start sent replconf ack
-		r.receiveRDB()
-		startOffset := r.stat.AofReceivedOffset
+		var startOffset int64
+		if resumed {
+			// no rdb, go on reading the aof files from the saved offset
+			startOffset = r.state.Offset
+			r.DbId = r.state.DbId
+		} else {
+			r.receiveRDB()
+			startOffset = r.stat.AofReceivedOffset
+		}
 		go r.receiveAOF(r.rd)
-		if r.opts.SyncRdb {
+		if r.opts.SyncRdb && !resumed && !r.opts.DisklessLoad {
 			r.sendRDB()
 		}
 		if r.opts.SyncAof {
@@ -116,39 +168,90 @@ func (r *syncStandaloneReader) sendReplconfListenPort() {
 	if err != nil {
 		log.Warnf("[%s] send replconf command to redis server failed. error=[%v]", r.stat.Name, err)
 	}
-}
 
-func (r *syncStandaloneReader) sendPSync() {
-	// send PSync
-	argv := []string{"PSYNC", "?", "-1"}
-	if config.Opt.Advanced.AwsPSync != "" {
-		argv = []string{config.Opt.Advanced.GetPSyncCommand(r.stat.Address), "?", "-1"}
+	// tell the source we can read the rdb of diskless replication
+	r.client.Send("replconf", "capa", "eof")
+	_, err = r.client.Receive()
+	if err != nil {
+		log.Warnf("[%s] send replconf capa command to redis server failed. error=[%v]", r.stat.Name, err)
 	}
-	r.client.Send(argv...)
+}
 
+// receivePSyncReply reads the reply of PSYNC, skipping the heartbeat newlines
+// the source may send before it.
+func receivePSyncReply(c *client.Redis) (string, error) {
 	// format: \n\n\n+<reply>\r\n
+	rd := c.BufioReader()
 	for {
-		bytes, err := r.rd.Peek(1)
+		bytes, err := rd.Peek(1)
 		if err != nil {
-			log.Panicf(err.Error())
+			return "", err
 		}
 		if bytes[0] != '\n' {
 			break
 		}
+		_, _ = rd.ReadByte()
+	}
+	return client.String(c.Receive())
+}
+
+// sendPSync sends PSYNC to the source. It returns true if the source accepts
+// to continue from the saved sync state, otherwise a full resync follows.
+func (r *syncStandaloneReader) sendPSync() bool {
+	// send PSync
+	replId, offset := "?", "-1"
+	if r.state != nil {
+		replId = r.state.ReplId
+		offset = strconv.FormatInt(r.stat.AofReceivedOffset+1, 10) // the first byte we want
+	}
+	cmd := "PSYNC"
+	if config.Opt.Advanced.AwsPSync != "" {
+		cmd = config.Opt.Advanced.GetPSyncCommand(r.stat.Address)
+	}
+	r.client.Send(cmd, replId, offset)
+	reply, err := receivePSyncReply(r.client)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	words := strings.Split(reply, " ")
+
+	// +CONTINUE [<new replid>]
+	if strings.EqualFold(words[0], "CONTINUE") {
+		if r.state == nil {
+			log.Panicf("[%s] source replied CONTINUE to a full resync request. reply=[%s]", r.stat.Name, reply)
+		}
+		r.stat.MasterReplId = r.state.ReplId
+		if len(words) > 1 {
+			r.stat.MasterReplId = words[1]
+		}
+		r.stat.Resumed = true
+		log.Infof("[%s] source accepted partial resync. replid=[%s], offset=[%d]", r.stat.Name, r.stat.MasterReplId, r.stat.AofReceivedOffset)
+		return true
+	}
+
+	// +FULLRESYNC <replid> <offset>
+	if len(words) != 3 || !strings.EqualFold(words[0], "FULLRESYNC") {
+		log.Panicf("[%s] invalid psync reply. reply=[%s]", r.stat.Name, reply)
+	}
+	if r.state != nil {
+		log.Warnf("[%s] source refused partial resync, start full resync. reply=[%s]", r.stat.Name, reply)
+		r.state = nil
+		utils.CreateEmptyDir(r.stat.Dir)
 	}
-	reply := r.client.ReceiveString()
-	masterOffset, err := strconv.Atoi(strings.Split(reply, " ")[2])
+	masterOffset, err := strconv.ParseInt(words[2], 10, 64)
 	if err != nil {
 		log.Panicf(err.Error())
 	}
-	r.stat.AofReceivedOffset = int64(masterOffset)
+	r.stat.MasterReplId = words[1]
+	r.stat.AofReceivedOffset = masterOffset
+	return false
 }
 
 func (r *syncStandaloneReader) receiveRDB() {
 	log.Debugf("[%s] source db is doing bgsave.", r.stat.Name)
 	r.stat.Status = kWaitBgsave
 	timeStart := time.Now()
-	// format: \n\n\n$<length>\r\n<rdb>
+	// format: \n\n\n$<length>\r\n<rdb> or \n\n\n$EOF:<mark>\r\n<rdb><mark>
 	for {
 		b, err := r.rd.ReadByte()
 		if err != nil {
@@ -168,13 +271,31 @@ func (r *syncStandaloneReader) receiveRDB() {
 		log.Panicf(err.Error())
 	}
 	lengthStr = strings.TrimSpace(lengthStr)
-	length, err := strconv.ParseInt(lengthStr, 10, 64)
-	if err != nil {
-		log.Panicf(err.Error())
+	var src io.Reader
+	length := int64(-1) // -1 if the size is unknown
+	if strings.HasPrefix(lengthStr, "EOF:") {
+		// diskless replication, format: $EOF:<mark>\r\n<rdb><mark>
+		mark := lengthStr[len("EOF:"):]
+		if len(mark) != rdbEOFMarkLen {
+			log.Panicf("[%s] invalid rdb eof mark. line=[%s]", r.stat.Name, lengthStr)
+		}
+		log.Debugf("[%s] rdb file size: [unknown], source is using diskless replication", r.stat.Name)
+		r.stat.RdbFileSizeHuman = "unknown"
+		src = newEOFMarkReader(r.rd, []byte(mark))
+	} else {
+		length, err = strconv.ParseInt(lengthStr, 10, 64)
+		if err != nil {
+			log.Panicf(err.Error())
+		}
+		log.Debugf("[%s] rdb file size: [%v]", r.stat.Name, humanize.IBytes(uint64(length)))
+		r.stat.RdbFileSizeBytes = length
+		r.stat.RdbFileSizeHuman = humanize.IBytes(uint64(length))
+		src = io.LimitReader(r.rd, length)
+	}
+	if r.opts.DisklessLoad {
+		r.loadRDBFromSocket(src, length)
+		return
 	}
-	log.Debugf("[%s] rdb file size: [%v]", r.stat.Name, humanize.IBytes(uint64(length)))
-	r.stat.RdbFileSizeBytes = length
-	r.stat.RdbFileSizeHuman = humanize.IBytes(uint64(length))
 
 	// create rdb file
 	r.stat.RdbFilePath, err = filepath.Abs(r.stat.Name + "/dump.rdb")
@@ -190,27 +311,29 @@ func (r *syncStandaloneReader) receiveRDB() {
 
 	// receive rdb
 	r.stat.Status = kReceiveRdb
-	remainder := length
 	const bufSize int64 = 32 * 1024 * 1024 // 32MB
 	buf := make([]byte, bufSize)
-	for remainder != 0 {
-		readOnce := bufSize
-		if remainder < readOnce {
-			readOnce = remainder
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			_, werr := rdbFileHandle.Write(buf[:n])
+			if werr != nil {
+				log.Panicf(werr.Error())
+			}
+			r.updateRdbReceived(r.stat.RdbReceivedBytes+int64(n), length)
 		}
-		n, err := r.rd.Read(buf[:readOnce])
-		if err != nil {
-			log.Panicf(err.Error())
+		if err == io.EOF {
+			break
 		}
-		remainder -= int64(n)
-		_, err = rdbFileHandle.Write(buf[:n])
 		if err != nil {
 			log.Panicf(err.Error())
 		}
-
-		r.stat.RdbReceivedBytes += int64(n)
-		r.stat.RdbReceivedHuman = humanize.IBytes(uint64(r.stat.RdbReceivedBytes))
 	}
+	if length != -1 && r.stat.RdbReceivedBytes != length {
+		log.Panicf("[%s] rdb is truncated. received=[%d], length=[%d]", r.stat.Name, r.stat.RdbReceivedBytes, length)
+	}
+	r.stat.RdbFileSizeBytes = r.stat.RdbReceivedBytes
+	r.stat.RdbFileSizeHuman = humanize.IBytes(uint64(r.stat.RdbFileSizeBytes))
 	err = rdbFileHandle.Close()
 	if err != nil {
 		log.Panicf(err.Error())
@@ -218,15 +341,62 @@ func (r *syncStandaloneReader) receiveRDB() {
 	log.Debugf("[%s] save RDB finished. timeUsed=[%.2f]s", r.stat.Name, time.Since(timeStart).Seconds())
 }
 
+// updateRdbReceived updates the received bytes of rdb. When the size of the
+// rdb is unknown, the file size grows with the received bytes.
+func (r *syncStandaloneReader) updateRdbReceived(received int64, length int64) {
+	r.stat.RdbReceivedBytes = received
+	r.stat.RdbReceivedHuman = humanize.IBytes(uint64(received))
+	if length == -1 {
+		r.stat.RdbFileSizeBytes = received
+	}
+}
+
+// loadRDBFromSocket parses the rdb while it is received, without saving it to
+// disk. The source keeps the new writes in the replica output buffer until the
+// rdb is drained, so it is only suitable when the target is fast enough.
+// length is -1 if the size of the rdb is unknown.
+func (r *syncStandaloneReader) loadRDBFromSocket(src io.Reader, length int64) {
+	log.Debugf("[%s] start loading RDB from socket", r.stat.Name)
+	r.stat.Status = kSyncRdb
+	timeStart := time.Now()
+	updateFunc := func(offset int64) {
+		r.updateRdbReceived(offset, length)
+		r.stat.RdbSentBytes = offset
+		r.stat.RdbSentHuman = humanize.IBytes(uint64(offset))
+	}
+	if r.opts.SyncRdb {
+		rdbLoader := rdb.NewStreamLoader(r.stat.Name, updateFunc, src, r.ch)
+		r.DbId = rdbLoader.ParseRDB()
+	}
+	// drain what the loader has not read, the whole rdb if sync_rdb is false
+	n, err := io.Copy(io.Discard, src)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	updateFunc(r.stat.RdbReceivedBytes + n)
+	if length != -1 && r.stat.RdbReceivedBytes != length {
+		log.Panicf("[%s] rdb is truncated. received=[%d], length=[%d]", r.stat.Name, r.stat.RdbReceivedBytes, length)
+	}
+	r.stat.RdbFileSizeBytes = r.stat.RdbReceivedBytes
+	r.stat.RdbFileSizeHuman = humanize.IBytes(uint64(r.stat.RdbFileSizeBytes))
+	log.Debugf("[%s] load RDB from socket finished. timeUsed=[%.2f]s", r.stat.Name, time.Since(timeStart).Seconds())
+}
+
 func (r *syncStandaloneReader) receiveAOF(rd io.Reader) {
 	log.Debugf("[%s] start receiving aof data, and save to file", r.stat.Name)
 	aofWriter := rotate.NewAOFWriter(r.stat.Name, r.stat.Dir, r.stat.AofReceivedOffset)
 	defer aofWriter.Close()
 	buf := make([]byte, 16*1024) // 16KB is enough for writing file
 	for {
+		r.client.SetReadDeadline(time.Now().Add(aofReadTimeout))
 		n, err := rd.Read(buf)
 		if err != nil {
-			log.Panicf(err.Error())
+			r.reconnect(err)
+			rd = r.rd
+			continue
+		}
+		if config.Opt.Advanced.AofAbsoluteExpire {
+			r.aofClock.record(r.stat.AofReceivedOffset)
 		}
 		r.stat.AofReceivedBytes += int64(n)
 		r.stat.AofReceivedHuman = humanize.IBytes(uint64(r.stat.AofReceivedBytes))
@@ -235,6 +405,86 @@ func (r *syncStandaloneReader) receiveAOF(rd io.Reader) {
 	}
 }
 
+// reconnect redials the source until it accepts a partial resync from
+// AofReceivedOffset, then replaces r.client and r.rd. A full resync can not be
+// handled here because part of the data is already sent to the target.
+func (r *syncStandaloneReader) reconnect(cause error) {
+	log.Warnf("[%s] connection to source broken, reconnecting. offset=[%d], error=[%v]", r.stat.Name, r.stat.AofReceivedOffset, cause)
+	r.stat.Reconnecting = true
+	outageStart := time.Now()
+	r.stat.LastOutageTime = outageStart.Format(time.RFC3339)
+	backoff := reconnectMinBackoff
+	var c *client.Redis
+	for {
+		r.stat.ReconnectAttempts++
+		var err error
+		c, err = r.tryPartialResync()
+		if err == nil {
+			break
+		}
+		log.Warnf("[%s] reconnect failed, retry in %v. attempts=[%d], error=[%v]", r.stat.Name, backoff, r.stat.ReconnectAttempts, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+
+	r.clientLock.Lock()
+	r.client.Close()
+	r.client = c
+	r.rd = c.BufioReader()
+	r.clientLock.Unlock()
+
+	outage := time.Since(outageStart).Seconds()
+	r.stat.Reconnecting = false
+	r.stat.ReconnectCount++
+	r.stat.LastOutageSeconds = outage
+	r.stat.TotalOutageSeconds += outage
+	log.Infof("[%s] reconnected to source. replid=[%s], offset=[%d], outage=[%.2f]s", r.stat.Name, r.stat.MasterReplId, r.stat.AofReceivedOffset, outage)
+}
+
+// tryPartialResync dials the source and sends PSYNC with the current offset.
+// Network errors are returned to be retried, a refused partial resync is fatal.
+func (r *syncStandaloneReader) tryPartialResync() (*client.Redis, error) {
+	c, err := client.DialRedisClient(r.opts.Address, r.opts.Username, r.opts.Password, r.opts.Tls)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.TryDo("replconf", "listening-port", strconv.Itoa(config.Opt.Advanced.StatusPort))
+	if err != nil && !isReplyError(err) {
+		c.Close()
+		return nil, err
+	}
+	cmd := "PSYNC"
+	if config.Opt.Advanced.AwsPSync != "" {
+		cmd = config.Opt.Advanced.GetPSyncCommand(r.stat.Address)
+	}
+	err = c.TrySend(cmd, r.stat.MasterReplId, strconv.FormatInt(r.stat.AofReceivedOffset+1, 10))
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	reply, err := receivePSyncReply(c)
+	if err != nil && !isReplyError(err) {
+		c.Close()
+		return nil, err
+	}
+	words := strings.Split(reply, " ")
+	if err != nil || !strings.EqualFold(words[0], "CONTINUE") {
+		log.Panicf("[%s] source refused partial resync after reconnect, restart redis-shake to do a full sync. reply=[%s], error=[%v]", r.stat.Name, reply, err)
+	}
+	if len(words) > 1 {
+		r.stat.MasterReplId = words[1]
+	}
+	return c, nil
+}
+
+func isReplyError(err error) bool {
+	_, ok := err.(proto.RedisError)
+	return ok
+}
+
 func (r *syncStandaloneReader) sendRDB() {
 	// start parse rdb
 	log.Debugf("[%s] start sending RDB to target", r.stat.Name)
@@ -252,10 +502,16 @@ func (r *syncStandaloneReader) sendAOF(offset int64) {
 	time.Sleep(1 * time.Second) // wait for receiveAOF create aof file
 	aofReader := rotate.NewAOFReader(r.stat.Name, r.stat.Dir, offset)
 	defer aofReader.Close()
-	r.client.SetBufioReader(bufio.NewReader(aofReader))
+	rd := bufio.NewReader(aofReader)
+	protoReader := proto.NewReader(rd)
+	r.stat.AofSentOffset = offset
+	r.written = newWrittenOffsets(offset, r.DbId)
+	go r.saveState()
 	for {
-		argv := client.ArrayString(r.client.Receive())
-		r.stat.AofSentOffset = aofReader.Offset()
+		cmdOffset := r.stat.AofSentOffset
+		argv := client.ArrayString(protoReader.ReadReply())
+		// bytes buffered by rd are not parsed yet
+		r.stat.AofSentOffset = aofReader.Offset() - int64(rd.Buffered())
 		// select
 		if strings.EqualFold(argv[0], "select") {
 			DbId, err := strconv.Atoi(argv[1])
@@ -263,37 +519,81 @@ func (r *syncStandaloneReader) sendAOF(offset int64) {
 				log.Panicf(err.Error())
 			}
 			r.DbId = DbId
+			r.written.skip(r.stat.AofSentOffset, r.DbId)
 			continue
 		}
-		// ping
-		if strings.EqualFold(argv[0], "ping") {
+		if isSkippedAOFCommand(argv) {
+			r.written.skip(r.stat.AofSentOffset, r.DbId)
 			continue
 		}
-		// replconf @AWS
-		if strings.EqualFold(argv[0], "replconf") {
-			continue
-		}
-		// opinfo @Aliyun
-		if strings.EqualFold(argv[0], "opinfo") {
-			continue
-		}
-		// sentinel
-		if strings.EqualFold(argv[0], "publish") && strings.EqualFold(argv[1], "__sentinel__:hello") {
-			continue
+
+		if config.Opt.Advanced.AofAbsoluteExpire {
+			argv = absoluteExpire(argv, r.aofClock.timeAt(cmdOffset))
 		}
 
 		e := entry.NewEntry()
 		e.Argv = argv
 		e.DbId = r.DbId
+		e.OnWritten = r.written.track(r.stat.AofSentOffset, r.DbId)
 		r.ch <- e
 	}
 }
 
+// isSkippedAOFCommand returns true for the commands of the replication stream
+// that are not sent to the target.
+func isSkippedAOFCommand(argv []string) bool {
+	// ping
+	if strings.EqualFold(argv[0], "ping") {
+		return true
+	}
+	// replconf @AWS
+	if strings.EqualFold(argv[0], "replconf") {
+		return true
+	}
+	// opinfo @Aliyun
+	if strings.EqualFold(argv[0], "opinfo") {
+		return true
+	}
+	// sentinel
+	if strings.EqualFold(argv[0], "publish") && strings.EqualFold(argv[1], "__sentinel__:hello") {
+		return true
+	}
+	return false
+}
+
+// saveState saves the replication state once per second. The saved offset is
+// the one before which all the commands are written to the target, so the
+// commands written after it are sent again after a restart. The aof files
+// before the saved offset are removed then.
+func (r *syncStandaloneReader) saveState() {
+	var lastOffset int64 = -1
+	for range time.Tick(time.Second) {
+		offset, dbId := r.written.get()
+		if offset == lastOffset {
+			continue
+		}
+		saveSyncState(r.stat.Dir, &syncState{
+			ReplId: r.stat.MasterReplId,
+			Offset: offset,
+			DbId:   dbId,
+		})
+		rotate.RemoveAOFFilesBefore(r.stat.Dir, offset)
+		lastOffset = offset
+	}
+}
+
 // sendReplconfAck send replconf ack to master to keep heartbeat between redis-shake and source redis.
 func (r *syncStandaloneReader) sendReplconfAck() {
 	for range time.Tick(time.Millisecond * 100) {
-		if r.stat.AofReceivedOffset != 0 {
-			r.client.Send("replconf", "ack", strconv.FormatInt(r.stat.AofReceivedOffset, 10))
+		if r.stat.AofReceivedOffset == 0 || r.stat.Reconnecting {
+			continue
+		}
+		r.clientLock.Lock()
+		err := r.client.TrySend("replconf", "ack", strconv.FormatInt(r.stat.AofReceivedOffset, 10))
+		r.clientLock.Unlock()
+		if err != nil {
+			// receiveAOF will notice the broken connection and reconnect
+			log.Debugf("[%s] send replconf ack failed. error=[%v]", r.stat.Name, err)
 		}
 	}
 }
@@ -303,6 +603,9 @@ func (r *syncStandaloneReader) Status() interface{} {
 }
 
 func (r *syncStandaloneReader) StatusString() string {
+	if r.stat.Reconnecting {
+		return fmt.Sprintf("%s, reconnecting to source, attempts=[%d]", r.stat.Status, r.stat.ReconnectAttempts)
+	}
 	if r.stat.Status == kSyncRdb {
 		return fmt.Sprintf("%s, size=[%s/%s]", r.stat.Status, r.stat.RdbSentHuman, r.stat.RdbFileSizeHuman)
 	}
@@ -0,0 +1,116 @@
+package reader
+
+import (
+	"RedisShake/internal/log"
+	"RedisShake/internal/utils"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const syncStateFileName = "sync_state.json"
+
+// syncState is the replication state saved in the work dir of the sync reader.
+// It is used to send "PSYNC <replid> <offset>" after redis-shake restarts.
+type syncState struct {
+	ReplId string `json:"replid"`
+	Offset int64  `json:"offset"` // offset of the aof applied by the target
+	DbId   int    `json:"db"`     // db selected at Offset
+}
+
+func loadSyncState(dir string) *syncState {
+	path := filepath.Join(dir, syncStateFileName)
+	if !utils.IsExist(path) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Panicf("read sync state failed. path=[%s], error=[%v]", path, err)
+	}
+	state := new(syncState)
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		log.Warnf("invalid sync state, ignore it. path=[%s], error=[%v]", path, err)
+		return nil
+	}
+	return state
+}
+
+func saveSyncState(dir string, state *syncState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	// write to a temp file and rename it, so the state file is never half written
+	path := filepath.Join(dir, syncStateFileName)
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		log.Panicf("write sync state failed. path=[%s], error=[%v]", tmpPath, err)
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		log.Panicf("rename sync state failed. path=[%s], error=[%v]", path, err)
+	}
+}
+
+// writtenOffsets tracks the aof offsets of the entries sent to chan, and finds
+// the offset before which all the entries are written to the target. It is
+// the offset saved in the sync state, so no command is lost after a restart.
+type writtenOffsets struct {
+	mu      sync.Mutex
+	base    uint64 // seq of pending[0]
+	pending []pendingOffset
+	offset  int64 // all the entries before offset are written
+	dbId    int   // db selected at offset
+}
+
+type pendingOffset struct {
+	offset  int64 // offset after the command of the entry
+	dbId    int
+	written bool
+}
+
+func newWrittenOffsets(offset int64, dbId int) *writtenOffsets {
+	return &writtenOffsets{offset: offset, dbId: dbId}
+}
+
+// track returns the OnWritten of the entry of the command that ends at offset.
+func (t *writtenOffsets) track(offset int64, dbId int) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	seq := t.base + uint64(len(t.pending))
+	t.pending = append(t.pending, pendingOffset{offset: offset, dbId: dbId})
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.pending[seq-t.base].written = true
+		t.advance()
+	}
+}
+
+// skip records a command that ends at offset and is not sent to chan, such as
+// SELECT and PING.
+func (t *writtenOffsets) skip(offset int64, dbId int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, pendingOffset{offset: offset, dbId: dbId, written: true})
+	t.advance()
+}
+
+func (t *writtenOffsets) advance() {
+	for len(t.pending) > 0 && t.pending[0].written {
+		t.offset, t.dbId = t.pending[0].offset, t.pending[0].dbId
+		t.pending = t.pending[1:]
+		t.base++
+	}
+}
+
+// get returns the offset before which all the entries are written, and the
+// db selected at it.
+func (t *writtenOffsets) get() (int64, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offset, t.dbId
+}
@@ -0,0 +1,28 @@
+package reader
+
+import "testing"
+
+func TestWrittenOffsets(t *testing.T) {
+	written := newWrittenOffsets(100, 0)
+	first := written.track(110, 0)
+	written.skip(120, 1) // SELECT 1
+	second := written.track(130, 1)
+	third := written.track(140, 1)
+
+	check := func(wantOffset int64, wantDbId int) {
+		t.Helper()
+		offset, dbId := written.get()
+		if offset != wantOffset || dbId != wantDbId {
+			t.Fatalf("got offset=%d db=%d, want offset=%d db=%d", offset, dbId, wantOffset, wantDbId)
+		}
+	}
+	check(100, 0)
+	third() // written out of order, the offset waits for the entries before it
+	check(100, 0)
+	first()
+	check(120, 1) // the skipped SELECT after first is written too
+	second()
+	check(140, 1)
+	written.skip(150, 1)
+	check(150, 1)
+}
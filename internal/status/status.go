@@ -18,6 +18,8 @@ type Stat struct {
 	// function
 	TotalEntriesCount  EntryCount            `json:"total_entries_count"`
 	PerCmdEntriesCount map[string]EntryCount `json:"per_cmd_entries_count"`
+	// keys that are not migrated, by reason
+	SkippedKeysCount map[string]uint64 `json:"skipped_keys_count"`
 	// reader
 	Reader interface{} `json:"reader"`
 	// writer
@@ -61,6 +63,17 @@ func AddWriteCount(cmd string) {
 	}
 }
 
+// AddSkippedKey counts a key that is skipped instead of migrated, such as a
+// value that can not be rewritten.
+func AddSkippedKey(reason string) {
+	ch <- func() {
+		if stat.SkippedKeysCount == nil {
+			stat.SkippedKeysCount = make(map[string]uint64)
+		}
+		stat.SkippedKeysCount[reason] += 1
+	}
+}
+
 func Init(r Statusable, w Statusable) {
 	theReader = r
 	theWriter = w
@@ -109,7 +122,18 @@ func Init(r Statusable, w Statusable) {
 		}
 	}()
 
-	// run all func in ch
+	runCounters()
+}
+
+// InitCounters only runs the counters, without the reader, the writer and
+// the status port. It is used by the subcommands that decode values, such as
+// rdb verify, the counters block when ch is full otherwise.
+func InitCounters() {
+	runCounters()
+}
+
+// runCounters runs all func in ch.
+func runCounters() {
 	go func() {
 		for f := range ch {
 			f()
@@ -0,0 +1,22 @@
+package status
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInitCounters(t *testing.T) {
+	InitCounters()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*cap(ch); i++ {
+			AddSkippedKey("unknown_module") // blocks when ch is full and not consumed
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("AddSkippedKey blocks")
+	}
+}
@@ -27,25 +27,35 @@ func NewAOFReader(name string, dir string, offset int64) *AOFReader {
 }
 
 func (r *AOFReader) openFile(offset int64) {
-	r.filepath = fmt.Sprintf("%s/%d.aof", r.dir, r.offset)
+	// offset may point into the middle of a file when resuming from a saved
+	// offset, so open the last file that starts at or before it.
+	start := offset
+	for _, fileOffset := range listAOFFiles(r.dir) {
+		if fileOffset <= offset {
+			start = fileOffset
+		}
+	}
+	r.filepath = fmt.Sprintf("%s/%d.aof", r.dir, start)
 	var err error
 	r.file, err = os.OpenFile(r.filepath, os.O_RDONLY, 0644)
 	if err != nil {
 		log.Panicf(err.Error())
 	}
+	r.pos, err = r.file.Seek(offset-start, io.SeekStart)
+	if err != nil {
+		log.Panicf(err.Error())
+	}
 	r.offset = offset
-	r.pos = 0
-	log.Debugf("[%s] open file for read. filename=[%s]", r.name, r.filepath)
+	log.Debugf("[%s] open file for read. filename=[%s], pos=[%d]", r.name, r.filepath, r.pos)
 }
 
+// readNextFile opens the next file once it is created. The files read are
+// kept until the offset saved in the sync state has moved past them, see
+// RemoveAOFFilesBefore.
 func (r *AOFReader) readNextFile(offset int64) {
 	filepath := fmt.Sprintf("%s/%d.aof", r.dir, r.offset)
 	if utils.IsExist(filepath) {
 		r.Close()
-		err := os.Remove(r.filepath)
-		if err != nil {
-			return
-		}
 		r.openFile(offset)
 	}
 }
@@ -4,6 +4,10 @@ import (
 	"RedisShake/internal/log"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 const MaxFileSize = 1024 * 1024 * 1024 // 1G
@@ -12,6 +16,8 @@ type AOFWriter struct {
 	name string
 	dir  string
 
+	suffix string // of the file names, such as ".aof"
+
 	file     *os.File
 	offset   int64
 	filepath string
@@ -19,21 +25,28 @@ type AOFWriter struct {
 }
 
 func NewAOFWriter(name string, dir string, offset int64) *AOFWriter {
+	return NewAOFWriterWithSuffix(name, dir, offset, ".aof")
+}
+
+// NewAOFWriterWithSuffix is like NewAOFWriter, but the files are named with
+// the suffix, such as "0.jsonl".
+func NewAOFWriterWithSuffix(name string, dir string, offset int64, suffix string) *AOFWriter {
 	w := new(AOFWriter)
 	w.name = name
 	w.dir = dir
+	w.suffix = suffix
 	w.openFile(offset)
 	return w
 }
 
 func (w *AOFWriter) openFile(offset int64) {
-	w.filepath = fmt.Sprintf("%s/%d.aof", w.dir, w.offset)
+	w.offset = offset
+	w.filepath = fmt.Sprintf("%s/%d%s", w.dir, w.offset, w.suffix)
 	var err error
 	w.file, err = os.OpenFile(w.filepath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		log.Panicf(err.Error())
 	}
-	w.offset = offset
 	w.filesize = 0
 	log.Debugf("[%s] open file for write. filename=[%s]", w.name, w.filepath)
 }
@@ -69,3 +82,69 @@ func (w *AOFWriter) Close() {
 	}
 	log.Infof("[%s] close file. filename=[%s], filesize=[%d]", w.name, w.filepath, w.filesize)
 }
+
+// listAOFFiles returns the start offsets of the aof files in dir, in ascending order.
+func listAOFFiles(dir string) []int64 {
+	return listFiles(dir, ".aof")
+}
+
+func listFiles(dir string, suffix string) []int64 {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	var offsets []int64
+	for _, match := range matches {
+		offset, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(match), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// RemoveAOFFilesBefore removes the aof files in dir that end at or before
+// offset, they are not needed to resume from offset. The last file is kept.
+func RemoveAOFFilesBefore(dir string, offset int64) {
+	offsets := listAOFFiles(dir)
+	for i := 0; i+1 < len(offsets) && offsets[i+1] <= offset; i++ {
+		path := fmt.Sprintf("%s/%d.aof", dir, offsets[i])
+		if err := os.Remove(path); err != nil {
+			log.Warnf("remove aof file failed. filename=[%s], error=[%v]", path, err)
+			continue
+		}
+		log.Debugf("remove aof file. filename=[%s]", path)
+	}
+}
+
+// ListFiles returns the paths of the files written by AOFWriter with the
+// suffix in dir, in the order of their offsets.
+func ListFiles(dir string, suffix string) []string {
+	var paths []string
+	for _, offset := range listFiles(dir, suffix) {
+		paths = append(paths, fmt.Sprintf("%s/%d%s", dir, offset, suffix))
+	}
+	return paths
+}
+
+// AOFRange returns the range of offsets [begin, end] that is kept by the aof
+// files in dir. ok is false when there is no aof file in dir.
+func AOFRange(dir string) (begin int64, end int64, ok bool) {
+	return FileRange(dir, ".aof")
+}
+
+// FileRange is like AOFRange, but for the files with the suffix.
+func FileRange(dir string, suffix string) (begin int64, end int64, ok bool) {
+	offsets := listFiles(dir, suffix)
+	if len(offsets) == 0 {
+		return 0, 0, false
+	}
+	last := offsets[len(offsets)-1]
+	fi, err := os.Stat(fmt.Sprintf("%s/%d%s", dir, last, suffix))
+	if err != nil {
+		log.Panicf(err.Error())
+	}
+	return offsets[0], last + fi.Size(), true
+}
@@ -0,0 +1,31 @@
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRemoveAOFFilesBefore(t *testing.T) {
+	dir := t.TempDir()
+	for _, offset := range []int64{100, 200, 300} {
+		if err := os.WriteFile(fmt.Sprintf("%s/%d.aof", dir, offset), make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		offset int64
+		want   []int64
+	}{
+		{150, []int64{100, 200, 300}}, // resuming from 150 reads 100.aof
+		{200, []int64{200, 300}},
+		{250, []int64{200, 300}},
+		{1000, []int64{300}}, // the last file is being written
+	} {
+		RemoveAOFFilesBefore(dir, c.offset)
+		if got := listAOFFiles(dir); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("offset %d: got %v, want %v", c.offset, got, c.want)
+		}
+	}
+}
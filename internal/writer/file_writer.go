@@ -0,0 +1,186 @@
+package writer
+
+import (
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/utils"
+	"RedisShake/internal/utils/file_rotate"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const fileWriterFlushSize = 1024 * 1024 // bytes buffered before written to the file
+
+type FileWriterOptions struct {
+	Dir    string `mapstructure:"dir" default:""`
+	Format string `mapstructure:"format" default:"resp"` // resp or json
+}
+
+type fileWriter struct {
+	format string
+	file   *rotate.AOFWriter
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	written   []*entry.Entry // entries in buf, written after buf is flushed
+	dbId      int            // -1 before the first SELECT
+	timestamp int64          // seconds of the last #TS annotation
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	stat struct {
+		Name         string `json:"name"`
+		Status       string `json:"status"`
+		Dir          string `json:"dir"`
+		Format       string `json:"format"`
+		Entries      int64  `json:"entries"`
+		WrittenBytes int64  `json:"written_bytes"`
+		WrittenHuman string `json:"written_human"`
+	}
+}
+
+// NewFileWriter writes the commands to the rotated files in dir, instead of
+// sending them to redis. The resp format is the same as aof, with SELECT and
+// "#TS:" annotations. The json format writes an entry per line. It appends
+// to the files written before, which should be of the same format.
+func NewFileWriter(opts *FileWriterOptions) Writer {
+	w := new(fileWriter)
+	w.stat.Name = "file_writer"
+	w.stat.Dir = utils.GetAbsPath(opts.Dir)
+	w.stat.Format = opts.Format
+	w.format = opts.Format
+	w.dbId = -1
+	suffix := entry.FileSuffix(opts.Format)
+	if err := os.MkdirAll(w.stat.Dir, 0755); err != nil {
+		log.Panicf("create dir failed. dir=[%s], error=[%v]", w.stat.Dir, err)
+	}
+	if err := checkFileFormat(w.stat.Dir, opts.Format); err != nil {
+		log.Panicf("[%s] %v, please use another dir. dir=[%s]", w.stat.Name, err, w.stat.Dir)
+	}
+	_, end, _ := rotate.FileRange(w.stat.Dir, suffix)
+	w.file = rotate.NewAOFWriterWithSuffix(w.stat.Name, w.stat.Dir, end, suffix)
+	w.stat.Status = "writing"
+
+	w.stop = make(chan struct{})
+	w.wg.Add(1)
+	go w.flushLoop()
+	return w
+}
+
+// checkFileFormat returns an error if the files in dir are not of the
+// format, since file_reader can only read the files of one format.
+func checkFileFormat(dir string, format string) error {
+	for _, other := range []string{"resp", "json"} {
+		if other == format {
+			continue
+		}
+		if files := rotate.ListFiles(dir, entry.FileSuffix(other)); len(files) != 0 {
+			return fmt.Errorf("the files of format [%s] are written in the dir, files=%v", other, files)
+		}
+	}
+	// the first byte of a command or an annotation of resp, or a json line
+	first := map[string]string{"resp": "*#", "json": "{"}[format]
+	for _, path := range rotate.ListFiles(dir, entry.FileSuffix(format)) {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Panicf("open file failed. file_path=[%s], error=[%v]", path, err)
+		}
+		buf := make([]byte, 1)
+		n, _ := file.Read(buf)
+		_ = file.Close()
+		if n != 0 && bytes.IndexByte([]byte(first), buf[0]) == -1 {
+			return fmt.Errorf("the file is not of format [%s], file_path=[%s]", format, path)
+		}
+	}
+	return nil
+}
+
+// flushLoop writes the buffered entries every second, so the files are not
+// behind for long when there are few writes.
+func (w *fileWriter) flushLoop() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.flush()
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *fileWriter) flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	w.file.Write(w.buf.Bytes())
+	w.stat.WrittenBytes += int64(w.buf.Len())
+	w.stat.WrittenHuman = humanize.Bytes(uint64(w.stat.WrittenBytes))
+	w.stat.Status = fmt.Sprintf("[%s] entries written: %d, %s", w.stat.Name, w.stat.Entries, w.stat.WrittenHuman)
+	w.buf.Reset()
+	for _, e := range w.written {
+		e.Written()
+	}
+	w.written = w.written[:0]
+}
+
+func (w *fileWriter) Write(e *entry.Entry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if w.format == "json" {
+		w.buf.Write(e.MarshalJSONLine(now.UnixMilli()))
+	} else {
+		if now.Unix() != w.timestamp {
+			w.timestamp = now.Unix()
+			w.buf.WriteString("#TS:" + strconv.FormatInt(w.timestamp, 10) + "\r\n")
+		}
+		if e.DbId != w.dbId {
+			selectEntry := entry.NewEntry()
+			selectEntry.Argv = []string{"SELECT", strconv.Itoa(e.DbId)}
+			w.buf.Write(selectEntry.Serialize())
+			w.dbId = e.DbId
+		}
+		w.buf.Write(e.Serialize())
+	}
+	w.stat.Entries++
+	w.written = append(w.written, e)
+	if w.buf.Len() >= fileWriterFlushSize {
+		w.flush()
+	}
+}
+
+func (w *fileWriter) Close() {
+	close(w.stop)
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	w.file.Close()
+	log.Infof("[%s] files written. dir=[%s], entries=[%d], size=[%s]", w.stat.Name, w.stat.Dir, w.stat.Entries, w.stat.WrittenHuman)
+}
+
+func (w *fileWriter) Status() interface{} {
+	return w.stat
+}
+
+func (w *fileWriter) StatusString() string {
+	return w.stat.Status
+}
+
+func (w *fileWriter) StatusConsistent() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Len() == 0
+}
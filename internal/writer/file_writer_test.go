@@ -0,0 +1,78 @@
+package writer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"RedisShake/internal/entry"
+	"RedisShake/internal/utils/file_rotate"
+)
+
+func TestFileWriterJSON(t *testing.T) {
+	dir := t.TempDir()
+	argvs := [][]string{{"SET", "k", "v"}, {"SET", "k", "\xff\xfe"}, {"DEL", "k"}}
+	for round := 0; round < 2; round++ { // appended to the files written before
+		w := NewFileWriter(&FileWriterOptions{Dir: dir, Format: "json"})
+		for i, argv := range argvs {
+			e := entry.NewEntry()
+			e.DbId = i
+			e.Argv = argv
+			e.Parse()
+			w.Write(e)
+		}
+		w.Close()
+	}
+
+	var got [][]string
+	for _, path := range rotate.ListFiles(dir, ".jsonl") {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			e, _, err := entry.ParseJSONLine(scanner.Bytes())
+			if err != nil {
+				t.Fatalf("invalid line %q, error=[%v]", scanner.Text(), err)
+			}
+			if e.DbId != len(got)%len(argvs) {
+				t.Errorf("got db %d of %q", e.DbId, e.Argv)
+			}
+			got = append(got, e.Argv)
+		}
+		_ = file.Close()
+	}
+	if want := append(argvs, argvs...); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckFileFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := checkFileFormat(dir, "resp"); err != nil {
+		t.Errorf("empty dir: got error [%v]", err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("0.aof", "#TS:1628217470\r\n*1\r\n$4\r\nPING\r\n")
+	write("38.aof", "")
+	if err := checkFileFormat(dir, "resp"); err != nil {
+		t.Errorf("resp files: got error [%v]", err)
+	}
+	if err := checkFileFormat(dir, "json"); err == nil {
+		t.Errorf("resp files: want an error of json format")
+	}
+
+	// a json line in an aof file
+	write("100.aof", `{"ts":1628217470123,"db":0,"cmd":"PING","keys":[],"argv":["PING"]}`+"\n")
+	if err := checkFileFormat(dir, "resp"); err == nil {
+		t.Errorf("json line in aof file: want an error")
+	}
+}
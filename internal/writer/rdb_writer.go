@@ -0,0 +1,252 @@
+package writer
+
+import (
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/log"
+	"RedisShake/internal/rdb"
+	"RedisShake/internal/rdb/types"
+	"RedisShake/internal/utils"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+type RdbWriterOptions struct {
+	Filepath      string  `mapstructure:"filepath" default:""`
+	TargetVersion float64 `mapstructure:"target_version" default:"7.2"` // version of the redis that loads the rdb
+
+	// DeduplicateKeys is set if the reader may send a key more than once, see
+	// reader.MayDuplicateKeys. The keys written are kept in memory then.
+	DeduplicateKeys bool `mapstructure:"-"`
+}
+
+const rdbFunctionVersion = 10 // functions are saved since redis 7.0
+
+type rdbWriter struct {
+	tmpPath   string
+	file      *os.File
+	enc       *rdb.Encoder
+	keys      map[int]map[string]struct{} // the keys written of each db, nil if not deduplicated, see writeRestore
+	libraries map[string]struct{}         // the names of the function libraries written
+	skipped   map[string]bool             // the names of the commands skipped, to warn once
+
+	stat struct {
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Filepath   string `json:"filepath"`
+		RdbVersion int    `json:"rdb_version"`
+		Keys       int64  `json:"keys"`
+		Functions  int64  `json:"functions"`
+
+		DuplicateKeys      int64 `json:"duplicate_keys"`      // restored again, such as the keys returned twice by SCAN
+		DuplicateFunctions int64 `json:"duplicate_functions"` // libraries loaded again, such as the ones of every rdb file
+		SkippedCommands    int64 `json:"skipped_commands"`    // commands that can not be written to rdb
+
+		WrittenBytes int64  `json:"written_bytes"`
+		WrittenHuman string `json:"written_human"`
+	}
+}
+
+// NewRDBWriter writes the keys to an rdb file. The file is written to a
+// temporary file and renamed when it is closed, so the file at filepath is
+// always complete.
+func NewRDBWriter(opts *RdbWriterOptions) Writer {
+	w := new(rdbWriter)
+	w.stat.Name = "rdb_writer"
+	w.stat.Filepath = utils.GetAbsPath(opts.Filepath)
+	w.stat.RdbVersion = types.RDBVersionOf(opts.TargetVersion)
+	if w.stat.RdbVersion == 0 {
+		log.Panicf("[%s] target_version is required", w.stat.Name)
+	}
+	// the readers create the values that the version can load, and restore
+	// the values of all sizes since there is no limit of the proto of redis
+	config.Opt.Target.Version = opts.TargetVersion
+	config.Opt.Advanced.TargetRedisProtoMaxBulkLen = math.MaxUint64
+	if opts.DeduplicateKeys {
+		w.keys = make(map[int]map[string]struct{})
+	}
+	w.libraries = make(map[string]struct{})
+	w.skipped = make(map[string]bool)
+
+	w.tmpPath = fmt.Sprintf("%s.tmp-%d", w.stat.Filepath, os.Getpid())
+	var err error
+	w.file, err = os.OpenFile(w.tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Panicf("create file failed. file_path=[%s], error=[%v]", w.tmpPath, err)
+	}
+	w.enc = rdb.NewEncoder(w.file, w.stat.RdbVersion)
+	w.enc.WriteAux("redis-ver", fmt.Sprintf("%.1f.0", opts.TargetVersion))
+	w.enc.WriteAux("redis-bits", "64")
+	w.enc.WriteAux("ctime", strconv.FormatInt(time.Now().Unix(), 10))
+	w.stat.Status = "writing"
+	log.Infof("[%s] write rdb version [%d] to [%s]", w.stat.Name, w.stat.RdbVersion, w.tmpPath)
+	return w
+}
+
+func (w *rdbWriter) Write(e *entry.Entry) {
+	switch e.CmdName {
+	case "RESTORE":
+		w.writeRestore(e)
+	case "FUNCTION-LOAD":
+		w.writeFunction(e)
+	case "SCRIPT-LOAD":
+		w.enc.WriteAux("lua", e.Argv[2])
+	default:
+		// such as FT.CREATE of the RediSearch indexes in the module aux, and
+		// the commands of the types that target_version can not load
+		w.skip(e)
+	}
+	w.stat.WrittenBytes = w.enc.Bytes()
+	w.stat.WrittenHuman = humanize.Bytes(uint64(w.stat.WrittenBytes))
+	w.stat.Status = fmt.Sprintf("[%s] keys written: %d, %s", w.stat.Name, w.stat.Keys, w.stat.WrittenHuman)
+	e.Written()
+}
+
+// skip skips the command that can not be written to rdb, with a warning
+// once per command name.
+func (w *rdbWriter) skip(e *entry.Entry) {
+	if !w.skipped[e.CmdName] {
+		log.Warnf("[%s] skip the command that can not be written to rdb, the other %s commands are skipped silently. cmd=[%s]",
+			w.stat.Name, e.CmdName, e.String())
+		w.skipped[e.CmdName] = true
+	}
+	w.stat.SkippedCommands++
+}
+
+// writeFunction writes the library of FUNCTION LOAD [REPLACE] code. A library
+// is only written once, redis refuses to load an rdb with duplicate
+// libraries, so the library loaded again is skipped. The functions are
+// skipped if target_version is older than 7.0.
+func (w *rdbWriter) writeFunction(e *entry.Entry) {
+	if w.stat.RdbVersion < rdbFunctionVersion {
+		w.skip(e)
+		return
+	}
+	code := e.Argv[len(e.Argv)-1]
+	name := functionLibraryName(code)
+	if name == "" {
+		log.Warnf("[%s] skip the function library without a name. cmd=[%s]", w.stat.Name, e.String())
+		w.stat.SkippedCommands++
+		return
+	}
+	if _, ok := w.libraries[name]; ok {
+		log.Warnf("[%s] skip the function library that is written before. library=[%s]", w.stat.Name, name)
+		w.stat.DuplicateFunctions++
+		return
+	}
+	w.libraries[name] = struct{}{}
+	w.enc.WriteFunction(code)
+	w.stat.Functions++
+}
+
+// functionLibraryName returns the library name in the first line of the code,
+// such as mylib of "#!lua name=mylib".
+func functionLibraryName(code string) string {
+	line, _, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	for _, field := range strings.Fields(line)[1:] {
+		if name, ok := strings.CutPrefix(field, "name="); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// writeRestore writes the key of RESTORE key ttl payload [REPLACE] [ABSTTL]
+// [IDLETIME seconds] [FREQ frequency]. A key can only be written once, redis
+// refuses to load an rdb with duplicate keys, so the key restored again is
+// skipped if DeduplicateKeys is set.
+func (w *rdbWriter) writeRestore(e *entry.Entry) {
+	if len(e.Argv) < 4 {
+		log.Panicf("[%s] invalid restore command. cmd=[%s]", w.stat.Name, e.String())
+	}
+	key := e.Argv[1]
+	if w.keys != nil {
+		keys, ok := w.keys[e.DbId]
+		if !ok {
+			keys = make(map[string]struct{})
+			w.keys[e.DbId] = keys
+		}
+		if _, ok := keys[key]; ok {
+			log.Warnf("[%s] skip the key that is written before. db=[%d], key=[%s]", w.stat.Name, e.DbId, key)
+			w.stat.DuplicateKeys++
+			return
+		}
+		keys[key] = struct{}{}
+	}
+	w.stat.Keys++
+
+	ttl, err := strconv.ParseInt(e.Argv[2], 10, 64)
+	if err != nil {
+		log.Panicf("[%s] invalid ttl of restore command. cmd=[%s]", w.stat.Name, e.String())
+	}
+	typeByte, value, err := rdb.ParseValueDump(e.Argv[3])
+	if err != nil {
+		log.Panicf("[%s] invalid payload of restore command. key=[%s], error=[%v]", w.stat.Name, key, err)
+	}
+
+	absTTL := false
+	var idle, freq int64
+	for i := 4; i < len(e.Argv); i++ {
+		switch strings.ToUpper(e.Argv[i]) {
+		case "ABSTTL":
+			absTTL = true
+		case "IDLETIME", "FREQ":
+			if i+1 >= len(e.Argv) {
+				log.Panicf("[%s] invalid restore command. cmd=[%s]", w.stat.Name, e.String())
+			}
+			n, err := strconv.ParseInt(e.Argv[i+1], 10, 64)
+			if err != nil {
+				log.Panicf("[%s] invalid restore command. cmd=[%s]", w.stat.Name, e.String())
+			}
+			if strings.EqualFold(e.Argv[i], "IDLETIME") {
+				idle = n
+			} else {
+				freq = n
+			}
+			i++
+		}
+	}
+	expireAt := ttl
+	if ttl != 0 && !absTTL {
+		expireAt = time.Now().UnixMilli() + ttl
+	}
+	w.enc.WriteKey(e.DbId, key, typeByte, value, expireAt, idle, freq)
+}
+
+func (w *rdbWriter) Close() {
+	w.enc.Close()
+	if err := w.file.Sync(); err != nil {
+		log.Panicf("sync file failed. file_path=[%s], error=[%v]", w.tmpPath, err)
+	}
+	if err := w.file.Close(); err != nil {
+		log.Panicf("close file failed. file_path=[%s], error=[%v]", w.tmpPath, err)
+	}
+	if err := os.Rename(w.tmpPath, w.stat.Filepath); err != nil {
+		log.Panicf("rename file failed. from=[%s], to=[%s], error=[%v]", w.tmpPath, w.stat.Filepath, err)
+	}
+	w.stat.WrittenBytes = w.enc.Bytes()
+	w.stat.WrittenHuman = humanize.Bytes(uint64(w.stat.WrittenBytes))
+	w.stat.Status = fmt.Sprintf("[%s] rdb file written: %d keys, %s", w.stat.Name, w.stat.Keys, w.stat.WrittenHuman)
+	log.Infof("[%s] rdb file written. file_path=[%s], keys=[%d], size=[%s]", w.stat.Name, w.stat.Filepath, w.stat.Keys, w.stat.WrittenHuman)
+}
+
+func (w *rdbWriter) Status() interface{} {
+	return w.stat
+}
+
+func (w *rdbWriter) StatusString() string {
+	return w.stat.Status
+}
+
+func (w *rdbWriter) StatusConsistent() bool {
+	return true // the entries are written when Write returns
+}
@@ -0,0 +1,107 @@
+package writer
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"RedisShake/internal/config"
+	"RedisShake/internal/entry"
+	"RedisShake/internal/rdb"
+)
+
+// testDump returns the payload of DUMP of the value with the checksum
+// disabled.
+func testDump(typeByte byte, value string) string {
+	return string(typeByte) + value + "\x0b\x00" + strings.Repeat("\x00", 8)
+}
+
+const testLibrary = "#!lua name=mylib\nredis.register_function('f', function() return 1 end)"
+
+func TestRDBWriter(t *testing.T) {
+	defer func(opt config.ShakeOptions) { config.Opt = opt }(config.Opt)
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	w := NewRDBWriter(&RdbWriterOptions{Filepath: path, TargetVersion: 7.2, DeduplicateKeys: true})
+	for _, argv := range [][]string{
+		{"restore", "k", "0", testDump(0, "\x02v1")},
+		{"FT.CREATE", "idx", "ON", "HASH", "SCHEMA", "f", "TEXT"},
+		{"restore", "k", "0", testDump(0, "\x02v2"), "replace"}, // returned twice by SCAN
+		{"FT.ALIASADD", "alias", "idx"},
+		{"restore", "k2", "0", testDump(0, "\x02v3"), "idletime", "10"},
+		{"function", "load", "replace", testLibrary},
+		{"function", "load", "replace", testLibrary}, // in every rdb file
+		{"function", "load", "return 1"},             // without a name
+	} {
+		e := entry.NewEntry()
+		e.Argv = argv
+		e.Parse()
+		w.Write(e)
+	}
+	w.Close()
+	stat := w.(*rdbWriter).stat
+	if stat.Keys != 2 || stat.DuplicateKeys != 1 || stat.Functions != 1 || stat.DuplicateFunctions != 1 || stat.SkippedCommands != 3 {
+		t.Errorf("got keys=%d, duplicate keys=%d, functions=%d, duplicate functions=%d, skipped commands=%d",
+			stat.Keys, stat.DuplicateKeys, stat.Functions, stat.DuplicateFunctions, stat.SkippedCommands)
+	}
+
+	// the rdb keeps the first value of the duplicate key
+	ch := make(chan *entry.Entry, 16)
+	rdb.NewLoader("test", nil, path, ch).ParseRDB() // the checksum is verified
+	close(ch)
+	var got [][]string
+	for e := range ch {
+		if e.Argv[0] == "function" {
+			if want := []string{"function", "load", "replace", testLibrary}; !reflect.DeepEqual(e.Argv, want) {
+				t.Errorf("got %q, want %q", e.Argv, want)
+			}
+			continue
+		}
+		got = append(got, e.Argv[:4])
+	}
+	want := [][]string{
+		{"restore", "k", "0", testDump(0, "\x02v1")},
+		{"restore", "k2", "0", testDump(0, "\x02v3")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range got {
+		// the checksum of the payload is recalculated
+		typeByte, value, err := rdb.ParseValueDump(got[i][3])
+		if got[i][1] != want[i][1] || err != nil || testDump(typeByte, string(value)) != want[i][3] {
+			t.Errorf("got %q, error=[%v], want %q", got[i], err, want[i])
+		}
+	}
+}
+
+func TestRDBWriterOldTarget(t *testing.T) {
+	defer func(opt config.ShakeOptions) { config.Opt = opt }(config.Opt)
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	w := NewRDBWriter(&RdbWriterOptions{Filepath: path, TargetVersion: 6.2})
+	for _, argv := range [][]string{
+		{"restore", "k", "0", testDump(0, "\x02v1")},
+		{"function", "load", "replace", testLibrary}, // functions are saved since 7.0
+	} {
+		e := entry.NewEntry()
+		e.Argv = argv
+		e.Parse()
+		w.Write(e)
+	}
+	w.Close()
+	stat := w.(*rdbWriter).stat
+	if stat.RdbVersion != 9 || stat.Keys != 1 || stat.Functions != 0 || stat.SkippedCommands != 1 || w.(*rdbWriter).keys != nil {
+		t.Errorf("got rdb version=%d, keys=%d, functions=%d, skipped commands=%d", stat.RdbVersion, stat.Keys, stat.Functions, stat.SkippedCommands)
+	}
+
+	ch := make(chan *entry.Entry, 16)
+	rdb.NewLoader("test", nil, path, ch).ParseRDB()
+	close(ch)
+	var got []string
+	for e := range ch {
+		got = append(got, e.Argv[0]+" "+e.Argv[1])
+	}
+	if want := []string{"restore k"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
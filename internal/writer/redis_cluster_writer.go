@@ -52,24 +52,31 @@ func (r *RedisClusterWriter) loadClusterNodes(opts *RedisWriterOptions) {
 	}
 }
 
-func (r *RedisClusterWriter) Write(entry *entry.Entry) {
-	if len(entry.Slots) == 0 {
-		for _, writer := range r.writers {
-			writer.Write(entry)
+func (r *RedisClusterWriter) Write(e *entry.Entry) {
+	if len(e.Slots) == 0 {
+		// a copy for each writer, the entry is written after all of them
+		copies := make([]*entry.Entry, len(r.writers))
+		for i := range r.writers {
+			theCopy := *e
+			copies[i] = &theCopy
+		}
+		e.SplitWritten(copies)
+		for i, writer := range r.writers {
+			writer.Write(copies[i])
 		}
 		return
 	}
 
 	lastSlot := -1
-	for _, slot := range entry.Slots {
+	for _, slot := range e.Slots {
 		if lastSlot == -1 {
 			lastSlot = slot
 		}
 		if slot != lastSlot {
-			log.Panicf("CROSSSLOT Keys in request don't hash to the same slot. argv=%v", entry.Argv)
+			log.Panicf("CROSSSLOT Keys in request don't hash to the same slot. argv=%v", e.Argv)
 		}
 	}
-	r.router[lastSlot].Write(entry)
+	r.router[lastSlot].Write(e)
 }
 
 func (r *RedisClusterWriter) Consistent() bool {
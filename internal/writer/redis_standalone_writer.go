@@ -42,12 +42,27 @@ func NewRedisStandaloneWriter(opts *RedisWriterOptions) Writer {
 	rw.address = opts.Address
 	rw.stat.Name = "writer_" + strings.Replace(opts.Address, ":", "_", -1)
 	rw.client = client.NewRedisClient(opts.Address, opts.Username, opts.Password, opts.Tls)
+	rw.detectTargetVersion()
 	rw.chWaitReply = make(chan *entry.Entry, config.Opt.Advanced.PipelineCountLimit)
 	rw.chWg.Add(1)
 	go rw.processReply()
 	return rw
 }
 
+// detectTargetVersion records the version of the target in config.Opt.Target,
+// the lowest version is kept for cluster.
+func (w *redisStandaloneWriter) detectTargetVersion() {
+	version, err := w.client.ServerVersion()
+	if err != nil {
+		log.Warnf("[%s] detect target version failed, features that depend on it are disabled. error=[%v]", w.stat.Name, err)
+		return
+	}
+	log.Infof("[%s] target version: [%v]", w.stat.Name, version)
+	if config.Opt.Target.Version == 0 || version < config.Opt.Target.Version {
+		config.Opt.Target.Version = version
+	}
+}
+
 func (w *redisStandaloneWriter) Close() {
 	close(w.chWaitReply)
 	w.chWg.Wait()
@@ -103,6 +118,7 @@ func (w *redisStandaloneWriter) processReply() {
 		}
 		atomic.AddInt64(&w.stat.UnansweredBytes, -e.SerializedSize)
 		atomic.AddInt64(&w.stat.UnansweredEntries, -1)
+		e.Written()
 	}
 	w.chWg.Done()
 }
@@ -233,6 +233,34 @@ fp.write("""
 		},
 	},
 """)
+
+# commands of the other modules, the key is the first argument unless noted
+# RedisJSON and RedisTimeSeries, the rewrite commands of JSONObject and TSDBObject
+rejson_timeseries_commands = {
+    "JSON": ["JSON.DEL", "JSON.SET"],
+    "TIMESERIES": ["TS.ADD", "TS.ALTER", "TS.CREATE", "TS.DEL", "TS.DELETERULE", "TS.INCRBY", "TS.DECRBY", "TS.MADD", "TS.CREATERULE"],
+}
+# RedisBloom except bloom filter, the rewrite commands of CuckooObject, TopKObject, CMSObject and TDigestObject
+redisbloom_commands = {
+    "CUCKOO FILTER": ["CF.ADD", "CF.ADDNX", "CF.DEL", "CF.INSERT", "CF.INSERTNX", "CF.LOADCHUNK", "CF.RESERVE"],
+    "TOPK": ["TOPK.ADD", "TOPK.INCRBY", "TOPK.RESERVE"],
+    "CMS": ["CMS.INCRBY", "CMS.INITBYDIM", "CMS.INITBYPROB"],
+    "TDIGEST": ["TDIGEST.ADD", "TDIGEST.CREATE", "TDIGEST.RESET"],
+}
+module_commands = {**redisbloom_commands, **rejson_timeseries_commands}
+for group, cmd_names in module_commands.items():
+    for cmd_name in cmd_names:
+        lastkey, step = 0, 1
+        if cmd_name == "TS.MADD":  # TS.MADD key timestamp value [key timestamp value ...]
+            lastkey, step = -1, 3
+        elif cmd_name in ("TS.CREATERULE", "TS.DELETERULE"):  # source and destination key
+            lastkey = 1
+        fp.write(f'"{cmd_name}": ' + "{\n")
+        fp.write(f'"{group}",\n')
+        fp.write("[]keySpec{\n{\n")
+        fp.write('"index",\n1,\n"",\n0,\n')
+        fp.write(f'"range",\n{lastkey},\n{step},\n0,\n0,\n0,\n0,\n')
+        fp.write("},\n},\n},\n")
 fp.write('}\n')
 fp.close()
 os.system("go fmt table.go")